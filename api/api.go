@@ -3,12 +3,18 @@ package api
 import (
 	"database/sql"
 
+	"github.com/KKGo-Software-engineering/workshop-summer/api/budget"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/eslip"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/health"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/idempotency"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/notify"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/recurring"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/spender"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/transaction"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/zap"
@@ -19,33 +25,76 @@ type Server struct {
 	*echo.Echo
 }
 
-func New(db *sql.DB, cfg config.Config, logger *zap.Logger) *Server {
+func New(db *sql.DB, cfg config.Config, logger *zap.Logger) (*Server, error) {
 	e := echo.New()
+	e.Validator = validate.New(db)
 
 	e.Use(middleware.Logger())
 	e.Use(mlog.Middleware(logger))
 
 	v1 := e.Group("/api/v1")
+	idem := idempotency.Middleware(db, cfg.Idempotency.TTL)
+
+	notifier, err := notify.New(cfg.Notify, logger)
+	if err != nil {
+		return nil, err
+	}
+	alerter := budget.NewAlerter(db, notifier, logger)
 
 	v1.GET("/slow", health.Slow)
 	v1.GET("/health", health.Check(db))
-	v1.POST("/upload", eslip.Upload)
+	v1.POST("/upload", eslip.Upload, idem)
 
 	{
 		h := spender.New(cfg.FeatureFlag, db)
 		v1.GET("/spenders", h.GetAll)
-		v1.POST("/spenders", h.Create)
+		v1.POST("/spenders", h.Create, idem)
+		v1.GET("/spenders/:id", h.GetByID)
+		v1.PUT("/spenders/:id", h.Update)
+		v1.PATCH("/spenders/:id", h.Patch)
+		v1.DELETE("/spenders/:id", h.Delete)
 
 	}
 
 	{
-		h := transaction.New(cfg.FeatureFlag, db)
+		h := transaction.New(cfg.FeatureFlag, db).WithAlerter(alerter)
 		v1.GET("/spenders/:id/transactions", h.GetTransactionById)
 		v1.GET("/spenders/:id/transactions/summary", h.GetSpenderSummary)
+		v1.GET("/spenders/:id/transactions/summary/categories", h.GetSpenderCategorySummary)
+		v1.GET("/spenders/:id/transactions/summary/tags", h.GetSpenderTagSummary)
+		v1.GET("/spenders/:id/balance", h.GetSpenderBalance)
 		v1.GET("/transactions", h.GetAll)
-		v1.POST("/transactions", h.Create)
+		v1.POST("/transactions", h.Create, idem)
+		v1.GET("/transactions/:id", h.GetByID)
 		v1.PUT("/transactions/:id", h.Update)
+		v1.PATCH("/transactions/:id", h.Patch)
+		v1.DELETE("/transactions/:id", h.Delete)
+		v1.POST("/transactions/:id/restore", h.Restore)
+		v1.POST("/transfers", h.CreateTransfer, idem)
+	}
+
+	{
+		h := budget.New(cfg.FeatureFlag, db)
+		v1.GET("/spenders/:id/budgets", h.GetAll)
+		v1.POST("/spenders/:id/budgets", h.Create, idem)
+		v1.GET("/spenders/:id/budgets/status", h.GetStatus)
+		v1.GET("/spenders/:id/budgets/:budgetId", h.GetByID)
+		v1.PUT("/spenders/:id/budgets/:budgetId", h.Update)
+		v1.DELETE("/spenders/:id/budgets/:budgetId", h.Delete)
+	}
+
+	{
+		h := recurring.New(cfg.FeatureFlag, db)
+		v1.GET("/spenders/:id/recurring", h.GetAll)
+		v1.POST("/recurring", h.Create, idem)
+		v1.DELETE("/recurring/:id", h.Delete)
+	}
+
+	{
+		h := fx.New(cfg.FeatureFlag, db)
+		v1.POST("/fx-rates", h.Create)
+		v1.POST("/fx-rates/import", h.Import)
 	}
 
-	return &Server{e}
+	return &Server{e}, nil
 }
@@ -0,0 +1,148 @@
+package budget
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/notify"
+	"go.uber.org/zap"
+)
+
+// Thresholds are the shares of a budget, in percent, that raise an alert,
+// highest first.
+var Thresholds = []int{100, 80}
+
+const (
+	ownerStmt    = `SELECT name, email, currency, timezone FROM spender WHERE id = $1;`
+	categoryStmt = `SELECT ` + columns + ` FROM budget WHERE spender_id = $1 AND category = $2 ORDER BY id;`
+	alertStmt    = `INSERT INTO budget_alert (budget_id, threshold, period_start) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`
+	unalertStmt  = `DELETE FROM budget_alert WHERE budget_id = $1 AND threshold = $2 AND period_start = $3;`
+)
+
+// Alerter warns spenders whose expenses push a budget past a threshold.
+// Every threshold of a budget fires at most once per period.
+type Alerter struct {
+	db       *sql.DB
+	notifier notify.Notifier
+	logger   *zap.Logger
+}
+
+func NewAlerter(db *sql.DB, n notify.Notifier, logger *zap.Logger) *Alerter {
+	return &Alerter{db: db, notifier: n, logger: logger}
+}
+
+// Spent checks the budgets of the category in the background after an
+// expense dated on date was written, so the request does not wait for the
+// delivery.
+func (a *Alerter) Spent(ctx context.Context, spenderID int64, category string, date time.Time) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := a.Check(ctx, spenderID, category, date); err != nil {
+			a.logger.Error("budget alert error", zap.Int64("spender_id", spenderID), zap.String("category", category), zap.Error(err))
+		}
+	}()
+}
+
+// Check alerts on every budget of the category whose spend, in the period
+// that contains date, crossed a threshold not alerted yet. Only the highest
+// threshold crossed is sent, the lower ones are recorded with it so they
+// stay quiet for the rest of the period.
+func (a *Alerter) Check(ctx context.Context, spenderID int64, category string, date time.Time) error {
+	var name, email, home, tz string
+	if err := a.db.QueryRowContext(ctx, ownerStmt, spenderID).Scan(&name, &email, &home, &tz); err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return err
+	}
+	day := date.In(loc)
+
+	budgets, err := a.budgets(ctx, spenderID, category)
+	if err != nil {
+		return err
+	}
+
+	cv := fx.NewConverter(a.db, home, day)
+	for _, b := range budgets {
+		st, err := status(ctx, a.db, cv, b, day)
+		if err != nil {
+			return err
+		}
+
+		var recorded []int
+		for _, t := range Thresholds {
+			if st.Spent*100 < b.Amount*money.Amount(t) {
+				continue
+			}
+			fresh, err := a.record(ctx, alertStmt, b.ID, t, st.PeriodStart)
+			if err != nil {
+				return err
+			}
+			if fresh {
+				recorded = append(recorded, t)
+			}
+		}
+		if len(recorded) == 0 {
+			continue
+		}
+
+		alert := notify.Alert{
+			SpenderID:   spenderID,
+			Name:        name,
+			Email:       email,
+			BudgetID:    b.ID,
+			Category:    b.Category,
+			Period:      b.Period,
+			PeriodStart: st.PeriodStart,
+			PeriodEnd:   st.PeriodEnd,
+			Threshold:   recorded[0],
+			Currency:    home,
+			Amount:      b.Amount,
+			Spent:       st.Spent,
+			PercentUsed: st.PercentUsed,
+		}
+		if err := a.notifier.Notify(ctx, alert); err != nil {
+			// forget the thresholds so the next expense tries again
+			for _, t := range recorded {
+				if _, uerr := a.record(ctx, unalertStmt, b.ID, t, st.PeriodStart); uerr != nil {
+					a.logger.Error("forget budget alert error", zap.Int64("budget_id", b.ID), zap.Error(uerr))
+				}
+			}
+			return err
+		}
+		a.logger.Info("budget alert sent", zap.Int64("budget_id", b.ID), zap.Int("threshold", alert.Threshold))
+	}
+	return nil
+}
+
+func (a *Alerter) budgets(ctx context.Context, spenderID int64, category string) ([]Budget, error) {
+	rows, err := a.db.QueryContext(ctx, categoryStmt, spenderID, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		var b Budget
+		if err := rows.Scan(&b.ID, &b.SpenderID, &b.Category, &b.Period, &b.Amount); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// record runs stmt for one alert and reports whether it changed a row.
+func (a *Alerter) record(ctx context.Context, stmt string, budgetID int64, threshold int, periodStart string) (bool, error) {
+	res, err := a.db.ExecContext(ctx, stmt, budgetID, threshold, periodStart)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,116 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/notify"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type notifierFunc func(ctx context.Context, a notify.Alert) error
+
+func (f notifierFunc) Notify(ctx context.Context, a notify.Alert) error { return f(ctx, a) }
+
+func TestAlerterCheck(t *testing.T) {
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	date := time.Date(2024, 5, 15, 3, 0, 0, 0, time.UTC)
+
+	expectBudget := func(mock sqlmock.Sqlmock, spent string) {
+		mock.ExpectQuery(ownerStmt).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "email", "currency", "timezone"}).AddRow("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok"))
+		mock.ExpectQuery(categoryStmt).WithArgs(int64(1), "Food").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "spender_id", "category", "period", "amount"}).AddRow(7, 1, "Food", "monthly", "5000.00"))
+		mock.ExpectQuery(spentStmt).WithArgs(int64(1), "Food", time.Date(2024, 5, 1, 0, 0, 0, 0, bkk), time.Date(2024, 6, 1, 0, 0, 0, 0, bkk)).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "sum"}).AddRow("THB", spent))
+	}
+
+	t.Run("only the highest threshold crossed is sent", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		expectBudget(mock, "5100.00")
+		mock.ExpectExec(alertStmt).WithArgs(int64(7), 100, "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(alertStmt).WithArgs(int64(7), 80, "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		var sent []notify.Alert
+		n := notifierFunc(func(_ context.Context, a notify.Alert) error {
+			sent = append(sent, a)
+			return nil
+		})
+
+		err := NewAlerter(db, n, zap.NewNop()).Check(context.Background(), 1, "Food", date)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []notify.Alert{{
+			SpenderID:   1,
+			Name:        "HongJot",
+			Email:       "hong@jot.ok",
+			BudgetID:    7,
+			Category:    "Food",
+			Period:      "monthly",
+			PeriodStart: "2024-05-01",
+			PeriodEnd:   "2024-05-31",
+			Threshold:   100,
+			Currency:    "THB",
+			Amount:      money.MustParse("5000"),
+			Spent:       money.MustParse("5100"),
+			PercentUsed: 102,
+		}}, sent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a threshold already alerted in the period stays quiet", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		expectBudget(mock, "4500.00")
+		mock.ExpectExec(alertStmt).WithArgs(int64(7), 80, "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		n := notifierFunc(func(context.Context, notify.Alert) error {
+			t.Error("no alert expected")
+			return nil
+		})
+
+		err := NewAlerter(db, n, zap.NewNop()).Check(context.Background(), 1, "Food", date)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("below every threshold", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		expectBudget(mock, "3999.99")
+
+		n := notifierFunc(func(context.Context, notify.Alert) error {
+			t.Error("no alert expected")
+			return nil
+		})
+
+		err := NewAlerter(db, n, zap.NewNop()).Check(context.Background(), 1, "Food", date)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed delivery is forgotten so it is tried again", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		expectBudget(mock, "4000.00")
+		mock.ExpectExec(alertStmt).WithArgs(int64(7), 80, "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(unalertStmt).WithArgs(int64(7), 80, "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		n := notifierFunc(func(context.Context, notify.Alert) error {
+			return errors.New("mail server down")
+		})
+
+		err := NewAlerter(db, n, zap.NewNop()).Check(context.Background(), 1, "Food", date)
+
+		assert.EqualError(t, err, "mail server down")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
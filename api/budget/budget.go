@@ -0,0 +1,229 @@
+package budget
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	Monthly = "monthly"
+	Weekly  = "weekly"
+)
+
+// Budget caps what a spender means to spend on a category every month or
+// every week, in the home currency of the spender.
+type Budget struct {
+	ID        int64        `json:"id"`
+	SpenderID int64        `json:"spender_id"`
+	Category  string       `json:"category" validate:"required,max=50,nocontrol"`
+	Period    string       `json:"period" validate:"oneof=monthly weekly"`
+	Amount    money.Amount `json:"amount" validate:"gt=0,money"`
+}
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+type handler struct {
+	flag config.FeatureFlag
+	db   *sql.DB
+}
+
+func New(cfg config.FeatureFlag, db *sql.DB) *handler {
+	return &handler{cfg, db}
+}
+
+const (
+	columns    = `id, spender_id, category, period, amount`
+	cStmt      = `INSERT INTO budget (spender_id, category, period, amount) VALUES ($1, $2, $3, $4) RETURNING id;`
+	listStmt   = `SELECT ` + columns + ` FROM budget WHERE spender_id = $1 ORDER BY id;`
+	getStmt    = `SELECT ` + columns + ` FROM budget WHERE id = $1 AND spender_id = $2;`
+	uStmt      = `UPDATE budget SET category = $1, period = $2, amount = $3 WHERE id = $4 AND spender_id = $5 RETURNING id;`
+	dStmt      = `DELETE FROM budget WHERE id = $1 AND spender_id = $2;`
+	errUnique  = "23505"
+	errForeign = "23503"
+)
+
+func (h handler) Create(c echo.Context) error {
+	if !h.flag.EnableBudget {
+		return c.JSON(http.StatusForbidden, "budget feature is disabled")
+	}
+	spenderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid spender id"})
+	}
+
+	var b Budget
+	if err := c.Bind(&b); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid budget request"})
+	}
+	b.normalize()
+	if err := validate.Request(c, &b); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	logger := mlog.L(c)
+	b.SpenderID = int64(spenderID)
+	err = h.db.QueryRowContext(c.Request().Context(), cStmt, b.SpenderID, b.Category, b.Period, b.Amount).Scan(&b.ID)
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	logger.Info("create successfully", zap.Int64("id", b.ID))
+	return c.JSON(http.StatusCreated, b)
+}
+
+func (h handler) GetAll(c echo.Context) error {
+	spenderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid spender id"})
+	}
+
+	budgets, err := list(c.Request().Context(), h.db, spenderID)
+	if err != nil {
+		mlog.L(c).Error("query error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, budgets)
+}
+
+func (h handler) GetByID(c echo.Context) error {
+	spenderID, id, err := ids(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	var b Budget
+	err = h.db.QueryRowContext(c.Request().Context(), getStmt, id, spenderID).Scan(&b.ID, &b.SpenderID, &b.Category, &b.Period, &b.Amount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "budget not found"})
+	}
+	if err != nil {
+		mlog.L(c).Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, b)
+}
+
+func (h handler) Update(c echo.Context) error {
+	if !h.flag.EnableBudget {
+		return c.JSON(http.StatusForbidden, "budget feature is disabled")
+	}
+	spenderID, id, err := ids(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	var b Budget
+	if err := c.Bind(&b); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid budget request"})
+	}
+	b.normalize()
+	if err := validate.Request(c, &b); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	logger := mlog.L(c)
+	b.ID, b.SpenderID = int64(id), int64(spenderID)
+	err = h.db.QueryRowContext(c.Request().Context(), uStmt, b.Category, b.Period, b.Amount, b.ID, b.SpenderID).Scan(&b.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "budget not found"})
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	logger.Info("update successfully", zap.Int64("id", b.ID))
+	return c.JSON(http.StatusOK, b)
+}
+
+func (h handler) Delete(c echo.Context) error {
+	if !h.flag.EnableBudget {
+		return c.JSON(http.StatusForbidden, "budget feature is disabled")
+	}
+	spenderID, id, err := ids(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	logger := mlog.L(c)
+	res, err := h.db.ExecContext(c.Request().Context(), dStmt, id, spenderID)
+	if err != nil {
+		logger.Error("exec error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("rows affected error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if affected == 0 {
+		return c.JSON(http.StatusNotFound, Err{Message: "budget not found"})
+	}
+
+	logger.Info("delete successfully", zap.Int("id", id))
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (b *Budget) normalize() {
+	b.Category = strings.TrimSpace(b.Category)
+	b.Period = strings.ToLower(b.Period)
+}
+
+func ids(c echo.Context) (spenderID, id int, err error) {
+	if spenderID, err = strconv.Atoi(c.Param("id")); err != nil {
+		return 0, 0, errors.New("invalid spender id")
+	}
+	if id, err = strconv.Atoi(c.Param("budgetId")); err != nil || id == 0 {
+		return 0, 0, errors.New("invalid budget id")
+	}
+	return spenderID, id, nil
+}
+
+// writeError answers a failed write, telling apart a duplicate budget and a
+// spender that does not exist from other database errors.
+func writeError(c echo.Context, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case errUnique:
+			return c.JSON(http.StatusConflict, Err{Message: "a budget for this category and period already exists"})
+		case errForeign:
+			return c.JSON(http.StatusNotFound, Err{Message: "spender not found"})
+		}
+	}
+	mlog.L(c).Error("query row error", zap.Error(err))
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
+
+func list(ctx context.Context, db *sql.DB, spenderID int) ([]Budget, error) {
+	rows, err := db.QueryContext(ctx, listStmt, spenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []Budget{}
+	for rows.Next() {
+		var b Budget
+		if err := rows.Scan(&b.ID, &b.SpenderID, &b.Category, &b.Period, &b.Amount); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
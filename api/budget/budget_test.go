@@ -0,0 +1,180 @@
+package budget
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext(method, body string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validate.New(nil)
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "budgetId")
+	c.SetParamValues(params...)
+	return c, rec
+}
+
+func TestCreateBudget(t *testing.T) {
+	enabled := config.FeatureFlag{EnableBudget: true}
+
+	t.Run("create budget successfully", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).WithArgs(int64(1), "Food", "monthly", money.MustParse("5000")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		c, rec := newContext(http.MethodPost, `{"category": " Food ", "period": "Monthly", "amount": 5000}`, "1")
+		err := New(enabled, db).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"id": 7, "spender_id": 1, "category": "Food", "period": "monthly", "amount": 5000}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("create budget failed when feature toggle is disable", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{}`, "1")
+		err := New(config.FeatureFlag{}, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("create budget failed on an invalid period and amount", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{"category": "Food", "period": "daily", "amount": 0}`, "1")
+		err := New(enabled, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"period"`)
+		assert.Contains(t, rec.Body.String(), `"field":"amount"`)
+	})
+
+	t.Run("create budget twice for a category and period is a conflict", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).WillReturnError(&pq.Error{Code: errUnique})
+
+		c, rec := newContext(http.MethodPost, `{"category": "Food", "period": "weekly", "amount": 500}`, "1")
+		err := New(enabled, db).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("create budget for an unknown spender", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).WillReturnError(&pq.Error{Code: errForeign})
+
+		c, rec := newContext(http.MethodPost, `{"category": "Food", "period": "weekly", "amount": 500}`, "99")
+		err := New(enabled, db).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"message": "spender not found"}`, rec.Body.String())
+	})
+}
+
+func TestGetBudgets(t *testing.T) {
+	t.Run("get all budgets of a spender", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(listStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "spender_id", "category", "period", "amount"}).
+			AddRow(1, 1, "Food", "monthly", "5000.00").
+			AddRow(2, 1, "Travel", "weekly", "800.00"))
+
+		c, rec := newContext(http.MethodGet, "", "1")
+		err := New(config.FeatureFlag{}, db).GetAll(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[
+			{"id": 1, "spender_id": 1, "category": "Food", "period": "monthly", "amount": 5000},
+			{"id": 2, "spender_id": 1, "category": "Travel", "period": "weekly", "amount": 800}
+		]`, rec.Body.String())
+	})
+
+	t.Run("get budget that does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(getStmt).WithArgs(3, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "spender_id", "category", "period", "amount"}))
+
+		c, rec := newContext(http.MethodGet, "", "1", "3")
+		err := New(config.FeatureFlag{}, db).GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestUpdateBudget(t *testing.T) {
+	enabled := config.FeatureFlag{EnableBudget: true}
+
+	t.Run("update budget successfully", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(uStmt).WithArgs("Food", "weekly", money.MustParse("1200.50"), int64(3), int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		c, rec := newContext(http.MethodPut, `{"category": "Food", "period": "weekly", "amount": 1200.5}`, "1", "3")
+		err := New(enabled, db).Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id": 3, "spender_id": 1, "category": "Food", "period": "weekly", "amount": 1200.5}`, rec.Body.String())
+	})
+
+	t.Run("update budget of another spender", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(uStmt).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		c, rec := newContext(http.MethodPut, `{"category": "Food", "period": "weekly", "amount": 100}`, "2", "3")
+		err := New(enabled, db).Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestDeleteBudget(t *testing.T) {
+	enabled := config.FeatureFlag{EnableBudget: true}
+
+	t.Run("delete budget successfully", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectExec(dStmt).WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		c, rec := newContext(http.MethodDelete, "", "1", "3")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("delete budget that does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectExec(dStmt).WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		c, rec := newContext(http.MethodDelete, "", "1", "3")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,161 @@
+package budget
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const dateLayout = "2006-01-02"
+
+// Status compares a budget with what was spent on its category in the
+// period that contains the asked day. Remaining turns negative once the
+// budget is overspent.
+type Status struct {
+	Budget
+	PeriodStart string       `json:"period_start"`
+	PeriodEnd   string       `json:"period_end"`
+	Spent       money.Amount `json:"spent"`
+	Remaining   money.Amount `json:"remaining"`
+	PercentUsed float64      `json:"percent_used"`
+	Over        bool         `json:"over"`
+}
+
+type StatusResponse struct {
+	SpenderID int64    `json:"spender_id"`
+	Date      string   `json:"date"`
+	Currency  string   `json:"currency"`
+	Budgets   []Status `json:"budgets"`
+}
+
+const (
+	spenderStmt = `SELECT currency, timezone FROM spender WHERE id = $1;`
+	spentStmt   = `SELECT currency, sum(amount) FROM transaction_line WHERE spender_id = $1 AND category = $2 AND transaction_type = 'expense' AND deleted_at IS NULL AND date >= $3 AND date < $4 GROUP BY currency;`
+)
+
+// GetStatus reports every budget of a spender against the actual spend, for
+// the periods that contain the date query parameter, today when it is not
+// given. Expenses in other currencies are converted at the rate known on
+// that day.
+func (h handler) GetStatus(c echo.Context) error {
+	spenderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid spender id"})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	home, loc, err := spenderZone(ctx, h.db, spenderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "spender not found"})
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	day := time.Now().In(loc)
+	if v := c.QueryParam("date"); v != "" {
+		if day, err = time.ParseInLocation(dateLayout, v, loc); err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: "invalid query parameter: date"})
+		}
+	}
+
+	budgets, err := list(ctx, h.db, spenderID)
+	if err != nil {
+		logger.Error("query error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	cv := fx.NewConverter(h.db, home, day)
+	resp := StatusResponse{SpenderID: int64(spenderID), Date: day.Format(dateLayout), Currency: home, Budgets: []Status{}}
+	for _, b := range budgets {
+		st, err := status(ctx, h.db, cv, b, day)
+		if errors.Is(err, fx.ErrNoRate) {
+			return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+		}
+		if err != nil {
+			logger.Error("budget status error", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		resp.Budgets = append(resp.Budgets, st)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func spenderZone(ctx context.Context, db *sql.DB, spenderID int) (home string, loc *time.Location, err error) {
+	var tz string
+	if err := db.QueryRowContext(ctx, spenderStmt, spenderID).Scan(&home, &tz); err != nil {
+		return "", nil, err
+	}
+	if loc, err = time.LoadLocation(tz); err != nil {
+		return "", nil, err
+	}
+	return home, loc, nil
+}
+
+func status(ctx context.Context, db *sql.DB, cv *fx.Converter, b Budget, day time.Time) (Status, error) {
+	start, end := Window(b.Period, day)
+	spent, err := spent(ctx, db, cv, b, start, end)
+	if err != nil {
+		return Status{}, err
+	}
+
+	return Status{
+		Budget:      b,
+		PeriodStart: start.Format(dateLayout),
+		PeriodEnd:   end.AddDate(0, 0, -1).Format(dateLayout),
+		Spent:       spent,
+		Remaining:   b.Amount - spent,
+		PercentUsed: spent.Percent(b.Amount),
+		Over:        spent > b.Amount,
+	}, nil
+}
+
+// spent totals the expenses on the category of the budget from start until
+// end, in the currency of the converter.
+func spent(ctx context.Context, db *sql.DB, cv *fx.Converter, b Budget, start, end time.Time) (money.Amount, error) {
+	rows, err := db.QueryContext(ctx, spentStmt, b.SpenderID, b.Category, start, end)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var total money.Amount
+	for rows.Next() {
+		var currency string
+		var amount money.Amount
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return 0, err
+		}
+		converted, err := cv.Convert(ctx, amount, currency)
+		if err != nil {
+			return 0, err
+		}
+		total += converted
+	}
+	return total, rows.Err()
+}
+
+// Window returns the start of the budget period that contains day and the
+// start of the next one, in the location of day. Weeks start on Monday.
+func Window(period string, day time.Time) (start, end time.Time) {
+	y, m, d := day.Date()
+	if period == Weekly {
+		start = time.Date(y, m, d-(int(day.Weekday())+6)%7, 0, 0, 0, 0, day.Location())
+		return start, start.AddDate(0, 0, 7)
+	}
+	start = time.Date(y, m, 1, 0, 0, 0, 0, day.Location())
+	return start, start.AddDate(0, 1, 0)
+}
@@ -0,0 +1,100 @@
+package budget
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	day := time.Date(2024, 5, 19, 15, 0, 0, 0, bkk) // a Sunday
+
+	start, end := Window(Weekly, day)
+	assert.Equal(t, time.Date(2024, 5, 13, 0, 0, 0, 0, bkk), start)
+	assert.Equal(t, time.Date(2024, 5, 20, 0, 0, 0, 0, bkk), end)
+
+	start, end = Window(Monthly, day)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, bkk), start)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, bkk), end)
+}
+
+func TestGetStatus(t *testing.T) {
+	newContext := func(query string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/budgets/status?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		return c, rec
+	}
+
+	t.Run("budgets against the spend of their current period", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		bkk, _ := time.LoadLocation("Asia/Bangkok")
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+		mock.ExpectQuery(listStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "spender_id", "category", "period", "amount"}).
+			AddRow(1, 1, "Food", "monthly", "5000.00").
+			AddRow(2, 1, "Travel", "weekly", "800.00"))
+		mock.ExpectQuery(spentStmt).WithArgs(int64(1), "Food", time.Date(2024, 5, 1, 0, 0, 0, 0, bkk), time.Date(2024, 6, 1, 0, 0, 0, 0, bkk)).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "sum"}).AddRow("THB", "3000.00").AddRow("USD", "20.00"))
+		mock.ExpectQuery(`SELECT base, rate FROM fx_rate WHERE ((base = $1 AND quote = $2) OR (base = $2 AND quote = $1)) AND effective_date <= $3 ORDER BY effective_date DESC, base = $1 DESC LIMIT 1;`).
+			WithArgs("USD", "THB", "2024-05-15").
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("USD", "36.5"))
+		mock.ExpectQuery(spentStmt).WithArgs(int64(1), "Travel", time.Date(2024, 5, 13, 0, 0, 0, 0, bkk), time.Date(2024, 5, 20, 0, 0, 0, 0, bkk)).
+			WillReturnRows(sqlmock.NewRows([]string{"currency", "sum"}).AddRow("THB", "1000.00"))
+
+		c, rec := newContext("date=2024-05-15")
+		err := New(config.FeatureFlag{}, db).GetStatus(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"spender_id": 1,
+			"date": "2024-05-15",
+			"currency": "THB",
+			"budgets": [
+				{"id": 1, "spender_id": 1, "category": "Food", "period": "monthly", "amount": 5000,
+				 "period_start": "2024-05-01", "period_end": "2024-05-31",
+				 "spent": 3730, "remaining": 1270, "percent_used": 74.6, "over": false},
+				{"id": 2, "spender_id": 1, "category": "Travel", "period": "weekly", "amount": 800,
+				 "period_start": "2024-05-13", "period_end": "2024-05-19",
+				 "spent": 1000, "remaining": -200, "percent_used": 125, "over": true}
+			]
+		}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown spender", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}))
+
+		c, rec := newContext("")
+		err := New(config.FeatureFlag{}, db).GetStatus(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+
+		c, rec := newContext("date=15/05/2024")
+		err := New(config.FeatureFlag{}, db).GetStatus(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"message": "invalid query parameter: date"}`, rec.Body.String())
+	})
+}
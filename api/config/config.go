@@ -3,9 +3,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 )
@@ -14,6 +20,9 @@ type Config struct {
 	Database    Database
 	Server      Server
 	FeatureFlag FeatureFlag
+	Idempotency Idempotency
+	Notify      Notify
+	Scheduler   Scheduler
 }
 
 func (c Config) PostgresURI() string {
@@ -24,14 +33,44 @@ type Server struct {
 	Port string `env:"SERVER_PORT"`
 }
 
+// Database sets where data is kept. With AutoMigrate off the server refuses
+// to start on a database with pending migrations instead of applying them,
+// leave the migrating to the migrate command.
 type Database struct {
 	PostgresURI string `env:"DATABASE_POSTGRES_URI,required"`
+	AutoMigrate bool   `env:"DATABASE_AUTO_MIGRATE" envDefault:"true"`
+}
+
+type Idempotency struct {
+	TTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+}
+
+// Scheduler sets how often due recurring transactions are written and
+// expired idempotency keys are purged.
+type Scheduler struct {
+	Interval time.Duration `env:"SCHEDULER_INTERVAL" envDefault:"1m"`
+}
+
+// Notify configures how budget alerts are delivered. Notifier is one of
+// log, smtp or webhook.
+type Notify struct {
+	Notifier   string `env:"NOTIFIER" envDefault:"log"`
+	SMTPAddr   string `env:"SMTP_ADDR" envDefault:"localhost:1025"`
+	SMTPFrom   string `env:"SMTP_FROM" envDefault:"alerts@hongjot.local"`
+	WebhookURL string `env:"NOTIFY_WEBHOOK_URL"`
 }
 
 type FeatureFlag struct {
 	EnableCreateSpender     bool `env:"ENABLE_CREATE_SPENDER"`
+	EnableUpdateSpender     bool `env:"ENABLE_UPDATE_SPENDER"`
+	EnableDeleteSpender     bool `env:"ENABLE_DELETE_SPENDER"`
 	EnableCreateTransaction bool `env:"ENABLE_CREATE_TRANSACTION"`
 	EnableUpdateTransaction bool `env:"ENABLE_UPDATE_TRANSACTION"`
+	EnableDeleteTransaction bool `env:"ENABLE_DELETE_TRANSACTION"`
+	EnableFxRateAdmin       bool `env:"ENABLE_FX_RATE_ADMIN"`
+	EnableBudget            bool `env:"ENABLE_BUDGET"`
+	EnableRecurring         bool `env:"ENABLE_RECURRING"`
+	EnableTransfer          bool `env:"ENABLE_TRANSFER"`
 }
 
 func Env(key string) string {
@@ -73,6 +112,21 @@ func parse(envPrefix string) (Config, error) {
 		return Config{}, errors.New("failed to parse feature flag config:" + err.Error())
 	}
 
+	idem := &Idempotency{}
+	if err := env.ParseWithOptions(idem, opts); err != nil {
+		return Config{}, errors.New("failed to parse idempotency config:" + err.Error())
+	}
+
+	notify := &Notify{}
+	if err := env.ParseWithOptions(notify, opts); err != nil {
+		return Config{}, errors.New("failed to parse notify config:" + err.Error())
+	}
+
+	sched := &Scheduler{}
+	if err := env.ParseWithOptions(sched, opts); err != nil {
+		return Config{}, errors.New("failed to parse scheduler config:" + err.Error())
+	}
+
 	port := Env("SERVER_PORT")
 	if port == "" {
 		port = "8080"
@@ -81,15 +135,28 @@ func parse(envPrefix string) (Config, error) {
 	return Config{
 		Database: Database{
 			PostgresURI: dbconf.PostgresURI,
+			AutoMigrate: dbconf.AutoMigrate,
 		},
 		Server: Server{
 			Port: port,
 		},
 		FeatureFlag: FeatureFlag{
 			EnableCreateSpender:     feats.EnableCreateSpender,
+			EnableUpdateSpender:     feats.EnableUpdateSpender,
+			EnableDeleteSpender:     feats.EnableDeleteSpender,
 			EnableCreateTransaction: feats.EnableCreateTransaction,
 			EnableUpdateTransaction: feats.EnableUpdateTransaction,
+			EnableDeleteTransaction: feats.EnableDeleteTransaction,
+			EnableFxRateAdmin:       feats.EnableFxRateAdmin,
+			EnableBudget:            feats.EnableBudget,
+			EnableRecurring:         feats.EnableRecurring,
+			EnableTransfer:          feats.EnableTransfer,
+		},
+		Idempotency: Idempotency{
+			TTL: idem.TTL,
 		},
+		Notify:    *notify,
+		Scheduler: *sched,
 	}, nil
 }
 
@@ -104,3 +171,66 @@ func Parse(envPrefix string) Config {
 
 	return config
 }
+
+// Load parses the config like Parse, but every time and with the error
+// returned, for commands that report a bad config rather than panic.
+func Load(envPrefix string) (Config, error) {
+	return parse(envPrefix)
+}
+
+// Validate reports the settings that parse but cannot work.
+func (c Config) Validate() error {
+	var errs []error
+	if u, err := url.Parse(c.Database.PostgresURI); err != nil || u.Scheme == "" {
+		errs = append(errs, errors.New("database postgres uri must be a postgres:// url"))
+	}
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server port %q must be a number between 1 and 65535", c.Server.Port))
+	}
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency ttl must be positive"))
+	}
+	if c.Scheduler.Interval <= 0 {
+		errs = append(errs, errors.New("scheduler interval must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+const redacted = "xxxxx"
+
+// Redacted returns a copy of the config safe to print: the database
+// password is masked and the webhook url, which carries its token in the
+// path, is hidden as a whole.
+func (c Config) Redacted() Config {
+	if u, err := url.Parse(c.Database.PostgresURI); err == nil && u.Scheme != "" {
+		c.Database.PostgresURI = u.Redacted()
+	} else if c.Database.PostgresURI != "" {
+		c.Database.PostgresURI = redacted
+	}
+	if c.Notify.WebhookURL != "" {
+		c.Notify.WebhookURL = redacted
+	}
+	return c
+}
+
+// WriteEnv writes every setting as NAME=value, named by its env tag.
+func (c Config) WriteEnv(w io.Writer) error {
+	return writeEnv(w, reflect.ValueOf(c))
+}
+
+func writeEnv(w io.Writer, v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := writeEnv(w, value); err != nil {
+				return err
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if _, err := fmt.Fprintf(w, "%s=%v\n", name, value.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+var ErrMultipleTags = errors.New("If-Match supports a single entity tag")
+
+// Format renders a row version as a strong entity tag.
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Set writes the ETag header of the row version to the response.
+func Set(c echo.Context, version int64) {
+	c.Response().Header().Set(HeaderETag, Format(version))
+}
+
+// IfMatch returns the row version the client expects from the If-Match
+// header. It returns nil when the request is unconditional, either without
+// the header or with "*". A tag that is not one of ours, including weak tags,
+// can never match and is returned as version 0, as is one beyond the INT
+// version column.
+func IfMatch(c echo.Context) (*int64, error) {
+	v := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if v == "" || v == "*" {
+		return nil, nil
+	}
+	if strings.Contains(v, ",") {
+		return nil, ErrMultipleTags
+	}
+
+	var version int64
+	if strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) && len(v) > 1 {
+		if n, err := strconv.ParseInt(v[1:len(v)-1], 10, 32); err == nil && n > 0 {
+			version = n
+		}
+	}
+	return &version, nil
+}
+
+// PreconditionFailed writes the response for a request whose If-Match does
+// not match the current row version.
+func PreconditionFailed(c echo.Context, current int64) error {
+	Set(c, current)
+	return c.JSON(http.StatusPreconditionFailed, map[string]string{
+		"message": "resource was modified, reload it and try again",
+	})
+}
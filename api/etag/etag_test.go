@@ -0,0 +1,56 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   *int64
+		err    error
+	}{
+		{name: "no header is unconditional", header: "", want: nil},
+		{name: "wildcard is unconditional", header: "*", want: nil},
+		{name: "strong tag", header: `"3"`, want: ptr(3)},
+		{name: "weak tag never matches", header: `W/"3"`, want: ptr(0)},
+		{name: "foreign tag never matches", header: `"abc"`, want: ptr(0)},
+		{name: "version beyond the column never matches", header: `"3000000000"`, want: ptr(0)},
+		{name: "list of tags", header: `"1", "2"`, err: ErrMultipleTags},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderIfMatch, tt.header)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			got, err := IfMatch(c)
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	Set(c, 7)
+
+	assert.Equal(t, `"7"`, rec.Header().Get(HeaderETag))
+}
+
+func ptr(v int64) *int64 {
+	return &v
+}
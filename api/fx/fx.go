@@ -0,0 +1,221 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const dateLayout = "2006-01-02"
+
+// Rate says one unit of Base is worth Rate units of Quote from Date on.
+type Rate struct {
+	Base  string      `json:"base" validate:"required,iso4217"`
+	Quote string      `json:"quote" validate:"required,iso4217,nefield=Base"`
+	Rate  json.Number `json:"rate" validate:"required"`
+	Date  string      `json:"date" validate:"required,datetime=2006-01-02"`
+}
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+type handler struct {
+	flag config.FeatureFlag
+	db   *sql.DB
+}
+
+func New(cfg config.FeatureFlag, db *sql.DB) *handler {
+	return &handler{cfg, db}
+}
+
+const (
+	upsertStmt = `INSERT INTO fx_rate (base, quote, rate, effective_date) VALUES ($1, $2, $3, $4) ON CONFLICT (base, quote, effective_date) DO UPDATE SET rate = EXCLUDED.rate;`
+	lookupStmt = `SELECT base, rate FROM fx_rate WHERE ((base = $1 AND quote = $2) OR (base = $2 AND quote = $1)) AND effective_date <= $3 ORDER BY effective_date DESC, base = $1 DESC LIMIT 1;`
+)
+
+// Create stores a batch of rates sent as a JSON array. A rate for a pair and
+// date that is already known is replaced.
+func (h handler) Create(c echo.Context) error {
+	if !h.flag.EnableFxRateAdmin {
+		return c.JSON(http.StatusForbidden, "fx rate admin feature is disabled")
+	}
+
+	var rates []Rate
+	if err := c.Bind(&rates); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid fx rate request"})
+	}
+
+	return h.save(c, rates)
+}
+
+// Import stores rates from a CSV body with the columns base, quote, rate and
+// date, so rates exported elsewhere can be loaded without any online source.
+func (h handler) Import(c echo.Context) error {
+	if !h.flag.EnableFxRateAdmin {
+		return c.JSON(http.StatusForbidden, "fx rate admin feature is disabled")
+	}
+
+	rates, err := ReadCSV(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	return h.save(c, rates)
+}
+
+func (h handler) save(c echo.Context, rates []Rate) error {
+	if len(rates) == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "no fx rates given"})
+	}
+	for i := range rates {
+		rates[i].Base = strings.ToUpper(rates[i].Base)
+		rates[i].Quote = strings.ToUpper(rates[i].Quote)
+		if err := validate.Request(c, &rates[i]); err != nil {
+			return validate.Fail(c, indexed(err, i))
+		}
+		if r, ok := new(big.Rat).SetString(rates[i].Rate.String()); !ok || r.Sign() <= 0 {
+			return validate.Fail(c, validate.Errors{{Field: fmt.Sprintf("[%d].rate", i), Message: "must be greater than 0"}})
+		}
+	}
+
+	logger := mlog.L(c)
+	if err := Save(c.Request().Context(), h.db, rates); err != nil {
+		logger.Error("save fx rates error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("fx rates saved", zap.Int("count", len(rates)))
+	return c.JSON(http.StatusCreated, rates)
+}
+
+// indexed prefixes the failing fields with the position of the rate in the batch.
+func indexed(err error, i int) error {
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	for j := range errs {
+		errs[j].Field = fmt.Sprintf("[%d].%s", i, errs[j].Field)
+	}
+	return errs
+}
+
+// Save writes the rates in one transaction, so a batch is stored whole or not at all.
+func Save(ctx context.Context, db *sql.DB, rates []Rate) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, r := range rates {
+		if _, err := tx.ExecContext(ctx, upsertStmt, r.Base, r.Quote, r.Rate.String(), r.Date); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReadCSV parses rates from CSV. A first line starting with "base" is taken
+// as the header and skipped.
+func ReadCSV(r io.Reader) ([]Rate, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 4
+	cr.TrimLeadingSpace = true
+
+	var rates []Rate
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		if line == 1 && strings.EqualFold(rec[0], "base") {
+			continue
+		}
+		rates = append(rates, Rate{Base: rec[0], Quote: rec[1], Rate: json.Number(rec[2]), Date: rec[3]})
+	}
+	return rates, nil
+}
+
+// ErrNoRate is returned when no rate between two currencies is known on a date.
+var ErrNoRate = errors.New("no fx rate")
+
+// Lookup finds the factor that converts an amount in from into to, using the
+// latest rate effective on the given date. A rate stored for the opposite
+// direction is inverted.
+func Lookup(ctx context.Context, db *sql.DB, from, to string, on time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	var base, rate string
+	err := db.QueryRowContext(ctx, lookupStmt, from, to, on.Format(dateLayout)).Scan(&base, &rate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoRate, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid fx rate %q from %s to %s", rate, base, to)
+	}
+	if base != from {
+		r.Inv(r)
+	}
+	return r, nil
+}
+
+// Converter converts amounts into one currency at the rates known on a day,
+// every rate is looked up once.
+type Converter struct {
+	db    *sql.DB
+	to    string
+	on    time.Time
+	rates map[string]*big.Rat
+}
+
+func NewConverter(db *sql.DB, to string, on time.Time) *Converter {
+	return &Converter{db: db, to: to, on: on, rates: map[string]*big.Rat{}}
+}
+
+func (cv *Converter) Convert(ctx context.Context, a money.Amount, from string) (money.Amount, error) {
+	rate, err := cv.Rate(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	return a.Mul(rate)
+}
+
+// Rate returns the factor Convert multiplies amounts in from by.
+func (cv *Converter) Rate(ctx context.Context, from string) (*big.Rat, error) {
+	rate, ok := cv.rates[from]
+	if !ok {
+		var err error
+		if rate, err = Lookup(ctx, cv.db, from, cv.to, cv.on); err != nil {
+			return nil, err
+		}
+		cv.rates[from] = rate
+	}
+	return rate, nil
+}
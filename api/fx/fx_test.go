@@ -0,0 +1,198 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate(t *testing.T) {
+	t.Run("store rates when feature toggle is enable", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		body := `[{"base": "usd", "quote": "THB", "rate": 36.5, "date": "2024-05-01"}, {"base": "JPY", "quote": "THB", "rate": "0.2345", "date": "2024-05-01"}]`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(upsertStmt).WithArgs("USD", "THB", "36.5", "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(upsertStmt).WithArgs("JPY", "THB", "0.2345", "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: true}, db)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reject rates when feature toggle is disable", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates", strings.NewReader(`[]`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: false}, nil)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("invalid rate is unprocessable entity", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		body := `[{"base": "USD", "quote": "THB", "rate": 36.5, "date": "2024-05-01"}, {"base": "BAHT", "quote": "THB", "rate": 0, "date": "01/05/2024"}]`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: true}, nil)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message": "validation failed", "errors": [
+			{"field": "[1].base", "message": "must be an ISO 4217 currency code"},
+			{"field": "[1].date", "message": "must be formatted as 2006-01-02"}
+		]}`, rec.Body.String())
+	})
+
+	t.Run("zero rate is unprocessable entity", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates", strings.NewReader(`[{"base": "USD", "quote": "THB", "rate": "0", "date": "2024-05-01"}]`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: true}, nil)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"[0].rate"`)
+	})
+}
+
+func TestImport(t *testing.T) {
+	t.Run("store rates from csv", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		body := "base,quote,rate,date\nUSD,THB,36.5,2024-05-01\nJPY, THB, 0.2345, 2024-05-01\n"
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates/import", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, "text/csv")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(upsertStmt).WithArgs("USD", "THB", "36.5", "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(upsertStmt).WithArgs("JPY", "THB", "0.2345", "2024-05-01").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: true}, db)
+		err := h.Import(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed csv is bad request", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates/import", strings.NewReader("USD,THB\n"))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: true}, nil)
+		err := h.Import(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("failed batch is rolled back", func(t *testing.T) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/fx-rates/import", strings.NewReader("USD,THB,36.5,2024-05-01\n"))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(upsertStmt).WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		h := New(config.FeatureFlag{EnableFxRateAdmin: true}, db)
+		err := h.Import(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLookup(t *testing.T) {
+	on := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("same currency needs no rate", func(t *testing.T) {
+		r, err := Lookup(context.Background(), nil, "THB", "THB", on)
+
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(1, 1), r)
+	})
+
+	t.Run("direct rate", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(lookupStmt).WithArgs("USD", "THB", "2024-05-10").
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("USD", "36.50000000"))
+
+		r, err := Lookup(context.Background(), db, "USD", "THB", on)
+
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(73, 2), r)
+	})
+
+	t.Run("opposite rate is inverted", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(lookupStmt).WithArgs("USD", "THB", "2024-05-10").
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("THB", "0.025"))
+
+		r, err := Lookup(context.Background(), db, "USD", "THB", on)
+
+		assert.NoError(t, err)
+		assert.Equal(t, big.NewRat(40, 1), r)
+	})
+
+	t.Run("unknown pair", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(lookupStmt).WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}))
+
+		_, err := Lookup(context.Background(), db, "JPY", "THB", on)
+
+		assert.ErrorIs(t, err, ErrNoRate)
+		assert.EqualError(t, err, "no fx rate from JPY to THB")
+	})
+}
@@ -0,0 +1,181 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+	maxKeyLength         = 255
+)
+
+const (
+	// claimStmt takes the key, or takes it over once the previous use expired.
+	claimStmt = `INSERT INTO idempotency_key (key, request_hash, expires_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status = NULL, content_type = NULL, body = NULL, expires_at = EXCLUDED.expires_at WHERE idempotency_key.expires_at < now();`
+	getStmt   = `SELECT request_hash, status, content_type, body FROM idempotency_key WHERE key = $1;`
+	saveStmt  = `UPDATE idempotency_key SET status = $2, content_type = $3, body = $4 WHERE key = $1;`
+	freeStmt  = `DELETE FROM idempotency_key WHERE key = $1;`
+	purgeStmt = `DELETE FROM idempotency_key WHERE expires_at < now();`
+)
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+// Middleware makes a POST safe to retry. The first request with a given
+// Idempotency-Key runs the handler and its response is kept for ttl, a replay
+// with the same body gets that response back without running the handler
+// again. Requests without the header pass straight through.
+func Middleware(db *sql.DB, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return c.JSON(http.StatusBadRequest, Err{Message: "Idempotency-Key is too long"})
+			}
+
+			logger := mlog.L(c)
+			ctx := c.Request().Context()
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, Err{Message: "failed to read request body"})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(c.Request(), body)
+
+			res, err := db.ExecContext(ctx, claimStmt, key, hash, time.Now().Add(ttl))
+			if err != nil {
+				logger.Error("claim idempotency key error", zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, err.Error())
+			}
+			if claimed, _ := res.RowsAffected(); claimed == 0 {
+				return replay(c, db, key, hash)
+			}
+
+			// the outcome is written even when the client gave up waiting,
+			// or its retry would find the key in progress until it expires
+			ctx = context.WithoutCancel(ctx)
+			free := func() {
+				if _, err := db.ExecContext(ctx, freeStmt, key); err != nil {
+					logger.Error("free idempotency key error", zap.Error(err))
+				}
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					free()
+					panic(r)
+				}
+			}()
+
+			rec := &recorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			// a failed request must stay retryable, so it gives the key back
+			if status := c.Response().Status; status >= http.StatusInternalServerError {
+				free()
+				return nil
+			}
+
+			contentType := c.Response().Header().Get(echo.HeaderContentType)
+			if _, err := db.ExecContext(ctx, saveStmt, key, c.Response().Status, contentType, rec.body.Bytes()); err != nil {
+				logger.Error("save idempotency key error", zap.Error(err))
+			}
+			return nil
+		}
+	}
+}
+
+// Purge deletes the keys that expired, which would otherwise only be reused
+// when a client happens to send the same key again. It returns how many it
+// deleted.
+func Purge(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, purgeStmt)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunPurge purges right away and then on every interval until ctx is done.
+func RunPurge(ctx context.Context, db *sql.DB, every time.Duration, logger *zap.Logger) {
+	if every <= 0 {
+		every = time.Minute
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		if n, err := Purge(ctx, db); err != nil && ctx.Err() == nil {
+			logger.Error("purge idempotency keys error", zap.Error(err))
+		} else if n > 0 {
+			logger.Info("expired idempotency keys purged", zap.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func replay(c echo.Context, db *sql.DB, key, hash string) error {
+	var storedHash string
+	var status sql.NullInt64
+	var contentType sql.NullString
+	var body []byte
+	err := db.QueryRowContext(c.Request().Context(), getStmt, key).Scan(&storedHash, &status, &contentType, &body)
+	if err != nil {
+		mlog.L(c).Error("get idempotency key error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	if storedHash != hash {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: "Idempotency-Key was already used with a different request"})
+	}
+	if !status.Valid {
+		return c.JSON(http.StatusConflict, Err{Message: "a request with this Idempotency-Key is still in progress"})
+	}
+
+	c.Response().Header().Set(HeaderReplayed, "true")
+	return c.Blob(int(status.Int64), contentType.String, body)
+}
+
+// requestHash identifies a request by its method, path and body so that a key
+// reused on another endpoint is treated as a different request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of everything the handler writes to the client.
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
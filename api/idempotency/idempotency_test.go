@@ -0,0 +1,204 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(t *testing.T, calls *int) (*echo.Echo, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	e := echo.New()
+	e.POST("/transactions", func(c echo.Context) error {
+		*calls++
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	}, Middleware(db, time.Hour))
+
+	return e, mock
+}
+
+func newRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	body := `{"amount": 1000}`
+	hash := requestHash(newRequest("", body), []byte(body))
+
+	t.Run("request without key passes through", func(t *testing.T) {
+		calls := 0
+		e, mock := setup(t, &calls)
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, newRequest("", body))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("first request runs the handler and stores the response", func(t *testing.T) {
+		calls := 0
+		e, mock := setup(t, &calls)
+		mock.ExpectExec(claimStmt).WithArgs("k1", hash, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(saveStmt).WithArgs("k1", http.StatusCreated, echo.MIMEApplicationJSON, []byte(`{"id":1}`+"\n")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, newRequest("k1", body))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 1, calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("replayed key returns the stored response", func(t *testing.T) {
+		calls := 0
+		e, mock := setup(t, &calls)
+		mock.ExpectExec(claimStmt).WithArgs("k1", hash, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(getStmt).WithArgs("k1").WillReturnRows(
+			sqlmock.NewRows([]string{"request_hash", "status", "content_type", "body"}).
+				AddRow(hash, http.StatusCreated, echo.MIMEApplicationJSON, []byte(`{"id":1}`)))
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, newRequest("k1", body))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 0, calls)
+		assert.Equal(t, "true", rec.Header().Get(HeaderReplayed))
+		assert.JSONEq(t, `{"id":1}`, rec.Body.String())
+	})
+
+	t.Run("same key with a different body is rejected", func(t *testing.T) {
+		calls := 0
+		e, mock := setup(t, &calls)
+		mock.ExpectExec(claimStmt).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(getStmt).WithArgs("k1").WillReturnRows(
+			sqlmock.NewRows([]string{"request_hash", "status", "content_type", "body"}).
+				AddRow(hash, http.StatusCreated, echo.MIMEApplicationJSON, []byte(`{"id":1}`)))
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, newRequest("k1", `{"amount": 2000}`))
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("key of a request in progress is a conflict", func(t *testing.T) {
+		calls := 0
+		e, mock := setup(t, &calls)
+		mock.ExpectExec(claimStmt).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(getStmt).WithArgs("k1").WillReturnRows(
+			sqlmock.NewRows([]string{"request_hash", "status", "content_type", "body"}).
+				AddRow(hash, nil, nil, nil))
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, newRequest("k1", body))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	t.Run("failed request gives the key back", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		e := echo.New()
+		e.POST("/spenders", func(c echo.Context) error {
+			return c.JSON(http.StatusInternalServerError, "boom")
+		}, Middleware(db, time.Hour))
+
+		mock.ExpectExec(claimStmt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(freeStmt).WithArgs("k2").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		req := httptest.NewRequest(http.MethodPost, "/spenders", strings.NewReader(`{}`))
+		req.Header.Set(HeaderIdempotencyKey, "k2")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMiddlewareCancelled(t *testing.T) {
+	t.Run("response is stored after the client gave up", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		e := echo.New()
+		e.POST("/transactions", func(c echo.Context) error {
+			cancel()
+			return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+		}, Middleware(db, time.Hour))
+
+		mock.ExpectExec(claimStmt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(saveStmt).WithArgs("k3", http.StatusCreated, echo.MIMEApplicationJSON, []byte(`{"id":1}`+"\n")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		e.ServeHTTP(httptest.NewRecorder(), newRequest("k3", `{}`).WithContext(ctx))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("panicking handler gives the key back", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		e := echo.New()
+		e.POST("/transactions", func(c echo.Context) error {
+			panic("boom")
+		}, Middleware(db, time.Hour))
+
+		mock.ExpectExec(claimStmt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(freeStmt).WithArgs("k4").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.PanicsWithValue(t, "boom", func() {
+			e.ServeHTTP(httptest.NewRecorder(), newRequest("k4", `{}`))
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPurge(t *testing.T) {
+	t.Run("purge deletes the expired keys", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectExec(purgeStmt).WillReturnResult(sqlmock.NewResult(0, 3))
+
+		n, err := Purge(context.Background(), db)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("purge fail when delete error", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectExec(purgeStmt).WillReturnError(assert.AnError)
+
+		_, err := Purge(context.Background(), db)
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
@@ -0,0 +1,189 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places kept, matching the DECIMAL(10,2)
+// amount columns.
+const Scale = 2
+
+const minorPerUnit = 100
+
+// Max is the largest amount the DECIMAL(10,2) amount columns hold.
+const Max Amount = 99_999_999_99
+
+// DefaultCurrency is the ISO 4217 code used when a spender or transaction
+// does not name one.
+const DefaultCurrency = "THB"
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Amount is an exact money amount counted in minor units (satang for THB),
+// so sums never drift the way float64 does. It reads and writes JSON as a
+// plain number, e.g. 1000 or 99.5, so clients see no difference.
+type Amount int64
+
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// Parse reads a decimal such as "1000", "-12.5" or "1e3". Digits beyond the
+// second decimal place are rounded half away from zero.
+func Parse(s string) (Amount, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, ErrInvalidAmount
+	}
+	return fromRat(r)
+}
+
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromFloat converts a float64 through its shortest decimal representation,
+// so FromFloat(0.1) is exactly 0.10.
+func FromFloat(f float64) Amount {
+	return MustParse(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func fromRat(r *big.Rat) (Amount, error) {
+	r = new(big.Rat).Mul(r, big.NewRat(minorPerUnit, 1))
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+
+	// round half away from zero: |2m| >= denom means the remainder is at least half
+	if new(big.Int).Abs(new(big.Int).Mul(m, big.NewInt(2))).Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+	return Amount(q.Int64()), nil
+}
+
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) Float64() float64 {
+	return float64(a) / minorPerUnit
+}
+
+// DivRound divides the amount, rounding half away from zero. It is meant for
+// averages and shares, where the result has to land on a whole minor unit.
+func (a Amount) DivRound(n int64) Amount {
+	if n == 0 {
+		return 0
+	}
+	q, r := int64(a)/n, int64(a)%n
+	if abs(r)*2 >= abs(n) {
+		if (a < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Amount(q)
+}
+
+// Mul multiplies the amount by an exact factor such as an exchange rate,
+// rounding half away from zero to a whole minor unit. It fails with
+// ErrInvalidAmount when the result does not fit.
+func (a Amount) Mul(r *big.Rat) (Amount, error) {
+	return fromRat(new(big.Rat).Mul(big.NewRat(int64(a), minorPerUnit), r))
+}
+
+// Percent returns a as a share of total, in percent with two decimals.
+func (a Amount) Percent(total Amount) float64 {
+	if total == 0 {
+		return 0
+	}
+	return Amount(int64(a) * 100 * minorPerUnit).DivRound(int64(total)).Float64()
+}
+
+// String renders the amount with both decimals, e.g. "1000.00".
+func (a Amount) String() string {
+	sign := ""
+	m := int64(a)
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, m/minorPerUnit, m%minorPerUnit)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	s := strings.TrimRight(strings.TrimRight(a.String(), "0"), ".")
+	return []byte(s), nil
+}
+
+// UnmarshalJSON accepts a JSON number as well as a numeric string.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	s := string(b)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	v, err := Parse(s)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidAmount, b)
+	}
+	*a = v
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns and aggregates over them.
+func (a *Amount) Scan(src any) error {
+	var v Amount
+	var err error
+	switch s := src.(type) {
+	case nil:
+		v = 0
+	case []byte:
+		v, err = Parse(string(s))
+	case string:
+		v, err = Parse(s)
+	case int64:
+		v = Amount(s * minorPerUnit)
+	case float64:
+		v = FromFloat(s)
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value implements driver.Valuer, the decimal text keeps the value exact on
+// its way into a DECIMAL column.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,145 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"1000", 100000},
+		{"0.1", 10},
+		{"-12.5", -1250},
+		{"1e3", 100000},
+		{"0.005", 1},
+		{"0.004", 0},
+		{"-0.005", -1},
+		{"19.999", 2000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("not a number", func(t *testing.T) {
+		_, err := Parse("ten baht")
+
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+	})
+}
+
+func TestSumDoesNotDrift(t *testing.T) {
+	var total Amount
+	for i := 0; i < 10; i++ {
+		total += MustParse("0.1")
+	}
+
+	assert.Equal(t, MustParse("1"), total)
+	assert.Equal(t, "1.00", total.String())
+}
+
+func TestDivRound(t *testing.T) {
+	assert.Equal(t, Amount(33), Amount(100).DivRound(3))
+	assert.Equal(t, Amount(67), Amount(200).DivRound(3))
+	assert.Equal(t, Amount(-67), Amount(-200).DivRound(3))
+	assert.Equal(t, Amount(0), Amount(100).DivRound(0))
+}
+
+func TestMul(t *testing.T) {
+	rate, _ := new(big.Rat).SetString("0.2345")
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1000", "234.5"},
+		{"0.1", "0.02"},
+		{"-0.1", "-0.02"},
+	}
+	for _, tt := range tests {
+		got, err := MustParse(tt.in).Mul(rate)
+
+		assert.NoError(t, err)
+		assert.Equal(t, MustParse(tt.want), got)
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := Amount(math.MaxInt64).Mul(big.NewRat(2, 1))
+
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+	})
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, 33.33, MustParse("100").Percent(MustParse("300")))
+	assert.Equal(t, 0.0, MustParse("100").Percent(0))
+}
+
+func TestJSON(t *testing.T) {
+	t.Run("marshal as a plain number", func(t *testing.T) {
+		b, err := json.Marshal(map[string]Amount{"a": MustParse("1000"), "b": MustParse("99.50"), "c": MustParse("-0.01")})
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"a":1000,"b":99.5,"c":-0.01}`, string(b))
+		assert.Contains(t, string(b), `"b":99.5`)
+	})
+
+	t.Run("unmarshal numbers and numeric strings", func(t *testing.T) {
+		var got struct {
+			A Amount `json:"a"`
+			B Amount `json:"b"`
+		}
+
+		err := json.Unmarshal([]byte(`{"a": 1000.25, "b": "12.30"}`), &got)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Amount(100025), got.A)
+		assert.Equal(t, Amount(1230), got.B)
+	})
+
+	t.Run("unmarshal rejects words", func(t *testing.T) {
+		var a Amount
+
+		assert.ErrorIs(t, json.Unmarshal([]byte(`"a lot"`), &a), ErrInvalidAmount)
+	})
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+		want Amount
+	}{
+		{"decimal bytes", []byte("1000.25"), 100025},
+		{"string", "0.10", 10},
+		{"integer", int64(7), 700},
+		{"float", 150.1, 15010},
+		{"null", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Amount
+
+			assert.NoError(t, a.Scan(tt.src))
+			assert.Equal(t, tt.want, a)
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	v, err := MustParse("1000").Value()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1000.00", v)
+}
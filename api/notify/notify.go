@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"go.uber.org/zap"
+)
+
+// Alert tells a spender that the spend on a category has reached Threshold
+// percent of its budget in the period from PeriodStart to PeriodEnd.
+type Alert struct {
+	SpenderID   int64        `json:"spender_id"`
+	Name        string       `json:"name"`
+	Email       string       `json:"email"`
+	BudgetID    int64        `json:"budget_id"`
+	Category    string       `json:"category"`
+	Period      string       `json:"period"`
+	PeriodStart string       `json:"period_start"`
+	PeriodEnd   string       `json:"period_end"`
+	Threshold   int          `json:"threshold"`
+	Currency    string       `json:"currency"`
+	Amount      money.Amount `json:"amount"`
+	Spent       money.Amount `json:"spent"`
+	PercentUsed float64      `json:"percent_used"`
+}
+
+func (a Alert) Subject() string {
+	if a.Threshold >= 100 {
+		return fmt.Sprintf("Budget for %s is used up", a.Category)
+	}
+	return fmt.Sprintf("Budget for %s is %d%% used", a.Category, a.Threshold)
+}
+
+func (a Alert) Body() string {
+	return fmt.Sprintf("Hi %s,\n\nYou have spent %s %s of your %s %s %s budget for %s (%.2f%%) between %s and %s.\n",
+		a.Name, a.Spent, a.Currency, a.Amount, a.Currency, a.Period, a.Category, a.PercentUsed, a.PeriodStart, a.PeriodEnd)
+}
+
+// Notifier delivers budget alerts.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// New returns the notifier named in the config.
+func New(cfg config.Notify, logger *zap.Logger) (Notifier, error) {
+	switch cfg.Notifier {
+	case "", "log":
+		return Log{Logger: logger}, nil
+	case "smtp":
+		return SMTP{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, Timeout: 10 * time.Second}, nil
+	case "webhook":
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("notifier webhook needs a webhook url")
+		}
+		return Webhook{URL: cfg.WebhookURL, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q", cfg.Notifier)
+}
+
+// Log only writes alerts to the log, for environments that deliver nothing.
+type Log struct {
+	Logger *zap.Logger
+}
+
+func (l Log) Notify(_ context.Context, a Alert) error {
+	l.Logger.Info("budget alert",
+		zap.Int64("spender_id", a.SpenderID),
+		zap.Int64("budget_id", a.BudgetID),
+		zap.String("category", a.Category),
+		zap.String("period_start", a.PeriodStart),
+		zap.Int("threshold", a.Threshold),
+		zap.Float64("percent_used", a.PercentUsed),
+	)
+	return nil
+}
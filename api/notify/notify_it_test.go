@@ -0,0 +1,47 @@
+//go:build integration
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSMTPIT(t *testing.T) {
+	t.Run("alert is delivered to the mail sink", func(t *testing.T) {
+		cfg := config.Parse("DOCKER").Notify
+		host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = SMTP{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom}.Notify(context.Background(), alert)
+		assert.NoError(t, err)
+
+		// MailHog lists what it caught on its HTTP API
+		resp, err := http.Get("http://" + net.JoinHostPort(host, "8025") + "/api/v2/search?kind=to&query=" + alert.Email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var found struct {
+			Total int `json:"total"`
+			Items []struct {
+				Content struct {
+					Headers map[string][]string `json:"Headers"`
+				} `json:"Content"`
+			} `json:"items"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&found))
+		if assert.NotZero(t, found.Total) {
+			assert.Equal(t, []string{alert.Subject()}, found.Items[0].Content.Headers["Subject"])
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var alert = Alert{
+	SpenderID:   1,
+	Name:        "HongJot",
+	Email:       "hong@jot.ok",
+	BudgetID:    7,
+	Category:    "Food",
+	Period:      "monthly",
+	PeriodStart: "2024-05-01",
+	PeriodEnd:   "2024-05-31",
+	Threshold:   80,
+	Currency:    "THB",
+	Amount:      money.MustParse("5000"),
+	Spent:       money.MustParse("4100"),
+	PercentUsed: 82,
+}
+
+// sink is a MailHog-style SMTP server that accepts one message and hands
+// over what it received.
+func sink(t *testing.T) (addr string, mail <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		reply("220 sink ready")
+
+		var got strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					got.WriteString(l)
+				}
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				ch <- got.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+func TestSMTP(t *testing.T) {
+	t.Run("mail the alert to the spender", func(t *testing.T) {
+		addr, mail := sink(t)
+
+		err := SMTP{Addr: addr, From: "alerts@hongjot.local"}.Notify(context.Background(), alert)
+
+		assert.NoError(t, err)
+		got := <-mail
+		assert.Contains(t, got, "To: hong@jot.ok\r\n")
+		assert.Contains(t, got, "Subject: Budget for Food is 80% used\r\n")
+		assert.Contains(t, got, "You have spent 4100.00 THB of your 5000.00 THB monthly budget for Food (82.00%)")
+	})
+
+	t.Run("encode the subject so the category cannot add headers", func(t *testing.T) {
+		a := alert
+		a.Category = "อาหาร\r\nBcc: x@evil"
+
+		header, _, _ := strings.Cut(string(SMTP{From: "alerts@hongjot.local"}.message(a)), "\r\n\r\n")
+
+		assert.NotContains(t, header, "\r\nBcc:")
+		assert.Contains(t, header, "Subject: =?utf-8?q?")
+	})
+
+	t.Run("give up on a relay that does not answer", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(time.Second)
+			}
+		}()
+
+		start := time.Now()
+		err = SMTP{Addr: ln.Addr().String(), From: "alerts@hongjot.local", Timeout: 50 * time.Millisecond}.Notify(context.Background(), alert)
+
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("spender without email", func(t *testing.T) {
+		a := alert
+		a.Email = ""
+
+		err := SMTP{Addr: "127.0.0.1:1", From: "alerts@hongjot.local"}.Notify(context.Background(), a)
+
+		assert.EqualError(t, err, "spender 1 has no email")
+	})
+}
+
+func TestWebhook(t *testing.T) {
+	t.Run("post the alert as json", func(t *testing.T) {
+		var got Alert
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			json.NewDecoder(r.Body).Decode(&got)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer srv.Close()
+
+		err := Webhook{URL: srv.URL}.Notify(context.Background(), alert)
+
+		assert.NoError(t, err)
+		assert.Equal(t, alert, got)
+	})
+
+	t.Run("a failed answer is an error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer srv.Close()
+
+		err := Webhook{URL: srv.URL}.Notify(context.Background(), alert)
+
+		assert.EqualError(t, err, "webhook answered 502 Bad Gateway")
+	})
+}
+
+func TestNew(t *testing.T) {
+	n, err := New(config.Notify{}, zap.NewNop())
+	assert.NoError(t, err)
+	assert.IsType(t, Log{}, n)
+
+	n, err = New(config.Notify{Notifier: "smtp", SMTPAddr: "mailhog:1025", SMTPFrom: "a@b.c"}, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Equal(t, SMTP{Addr: "mailhog:1025", From: "a@b.c", Timeout: 10 * time.Second}, n)
+
+	_, err = New(config.Notify{Notifier: "webhook"}, zap.NewNop())
+	assert.Error(t, err)
+
+	_, err = New(config.Notify{Notifier: "pigeon"}, zap.NewNop())
+	assert.EqualError(t, err, `unknown notifier "pigeon"`)
+}
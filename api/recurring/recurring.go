@@ -0,0 +1,174 @@
+package recurring
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const dateLayout = "2006-01-02"
+
+// Template is a transaction that repeats on the schedule of its Rule, from
+// StartsOn until Until when it is given. NextRun is the next occurrence the
+// scheduler will write, empty once the schedule has ended.
+type Template struct {
+	ID              int64        `json:"id"`
+	SpenderID       int64        `json:"spender_id" validate:"required,exists=spender"`
+	Amount          money.Amount `json:"amount" validate:"gt=0,money"`
+	Category        string       `json:"category" validate:"required,max=50,nocontrol"`
+	TransactionType string       `json:"transaction_type" validate:"oneof=income expense"`
+	Note            string       `json:"note" validate:"max=255"`
+	// Currency defaults to the home currency of the spender when left empty.
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	Rule     string `json:"rule" validate:"required,max=255"`
+	StartsOn string `json:"starts_on" validate:"required,datetime=2006-01-02"`
+	Until    string `json:"until,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	NextRun  string `json:"next_run,omitempty"`
+}
+
+type Err struct {
+	Message string `json:"message"`
+}
+
+type handler struct {
+	flag config.FeatureFlag
+	db   *sql.DB
+}
+
+func New(cfg config.FeatureFlag, db *sql.DB) *handler {
+	return &handler{cfg, db}
+}
+
+const (
+	columns  = `id, spender_id, amount, category, transaction_type, note, currency, rule, starts_on, until, next_run`
+	cStmt    = `INSERT INTO recurring (spender_id, amount, category, transaction_type, note, currency, rule, starts_on, until, next_run) VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), (SELECT currency FROM spender WHERE id = $1)), $7, $8, $9, $10) RETURNING id, currency;`
+	listStmt = `SELECT ` + columns + ` FROM recurring WHERE spender_id = $1 ORDER BY id;`
+	dStmt    = `DELETE FROM recurring WHERE id = $1;`
+)
+
+func (h handler) Create(c echo.Context) error {
+	if !h.flag.EnableRecurring {
+		return c.JSON(http.StatusForbidden, "recurring transaction feature is disabled")
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	var t Template
+	if err := c.Bind(&t); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid recurring transaction request"})
+	}
+	t.Currency = strings.ToUpper(t.Currency)
+	if err := validate.Request(c, &t); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	rule, err := ParseRule(t.Rule)
+	if err != nil {
+		return validate.Fail(c, validate.Errors{{Field: "rule", Message: err.Error()}})
+	}
+	start, _ := time.Parse(dateLayout, t.StartsOn)
+	rule = rule.Anchor(start)
+	first := rule.First(start)
+
+	var until any
+	if t.Until != "" {
+		end, _ := time.Parse(dateLayout, t.Until)
+		if end.Before(first) {
+			return validate.Fail(c, validate.Errors{{Field: "until", Message: "must not be before the first occurrence " + first.Format(dateLayout)}})
+		}
+		until = t.Until
+	}
+	t.Rule = rule.String()
+	t.NextRun = first.Format(dateLayout)
+
+	err = h.db.QueryRowContext(ctx, cStmt,
+		t.SpenderID, t.Amount, t.Category, t.TransactionType, t.Note, t.Currency, t.Rule, t.StartsOn, until, t.NextRun,
+	).Scan(&t.ID, &t.Currency)
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("create successfully", zap.Int64("id", t.ID))
+	return c.JSON(http.StatusCreated, t)
+}
+
+func (h handler) GetAll(c echo.Context) error {
+	spenderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid spender id"})
+	}
+
+	logger := mlog.L(c)
+	rows, err := h.db.QueryContext(c.Request().Context(), listStmt, spenderID)
+	if err != nil {
+		logger.Error("query error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer rows.Close()
+
+	templates := []Template{}
+	for rows.Next() {
+		var t Template
+		var startsOn time.Time
+		var until, nextRun sql.NullTime
+		err := rows.Scan(&t.ID, &t.SpenderID, &t.Amount, &t.Category, &t.TransactionType, &t.Note, &t.Currency, &t.Rule, &startsOn, &until, &nextRun)
+		if err != nil {
+			logger.Error("scan error", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		t.StartsOn = startsOn.Format(dateLayout)
+		if until.Valid {
+			t.Until = until.Time.Format(dateLayout)
+		}
+		if nextRun.Valid {
+			t.NextRun = nextRun.Time.Format(dateLayout)
+		}
+		templates = append(templates, t)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("rows error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, templates)
+}
+
+// Delete stops a schedule. Transactions it already wrote are kept.
+func (h handler) Delete(c echo.Context) error {
+	if !h.flag.EnableRecurring {
+		return c.JSON(http.StatusForbidden, "recurring transaction feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	logger := mlog.L(c)
+	res, err := h.db.ExecContext(c.Request().Context(), dStmt, id)
+	if err != nil {
+		logger.Error("exec error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("rows affected error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if affected == 0 {
+		return c.JSON(http.StatusNotFound, Err{Message: "recurring transaction not found"})
+	}
+
+	logger.Info("delete successfully", zap.Int("id", id))
+	return c.NoContent(http.StatusNoContent)
+}
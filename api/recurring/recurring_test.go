@@ -0,0 +1,89 @@
+package recurring
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func newContext(method, body string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	e.Validator = validate.New(nil)
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestCreateRecurring(t *testing.T) {
+	enabled := config.FeatureFlag{EnableRecurring: true}
+
+	t.Run("create recurring transaction starting on its first occurrence", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).
+			WithArgs(int64(1), money.MustParse("30000"), "Salary", "income", "", "", "FREQ=MONTHLY;BYMONTHDAY=25", "2024-05-01", nil, "2024-05-25").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(1, "THB"))
+
+		c, rec := newContext(http.MethodPost, `{"spender_id": 1, "amount": 30000, "category": "Salary", "transaction_type": "income", "rule": "freq=monthly;bymonthday=25", "starts_on": "2024-05-01"}`)
+		err := New(enabled, db).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{
+			"id": 1, "spender_id": 1, "amount": 30000, "category": "Salary", "transaction_type": "income", "note": "",
+			"currency": "THB", "rule": "FREQ=MONTHLY;BYMONTHDAY=25", "starts_on": "2024-05-01", "next_run": "2024-05-25"
+		}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("create recurring transaction fail when the rule is invalid", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{"spender_id": 1, "amount": 100, "category": "Rent", "transaction_type": "expense", "rule": "FREQ=HOURLY", "starts_on": "2024-05-01"}`)
+		err := New(enabled, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"rule"`)
+	})
+
+	t.Run("create recurring transaction fail when it ends before its first occurrence", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{"spender_id": 1, "amount": 100, "category": "Rent", "transaction_type": "expense", "rule": "FREQ=MONTHLY;BYMONTHDAY=25", "starts_on": "2024-05-01", "until": "2024-05-20"}`)
+		err := New(enabled, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "must not be before the first occurrence 2024-05-25")
+	})
+
+	t.Run("create recurring transaction fail when feature toggle is disable", func(t *testing.T) {
+		c, rec := newContext(http.MethodPost, `{}`)
+		err := New(config.FeatureFlag{}, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestDeleteRecurring(t *testing.T) {
+	t.Run("delete recurring transaction that does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectExec(dStmt).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		c, rec := newContext(http.MethodDelete, "")
+		c.SetParamNames("id")
+		c.SetParamValues("9")
+		err := New(config.FeatureFlag{EnableRecurring: true}, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,165 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// Rule is the subset of an iCalendar RRULE the scheduler understands, for
+// example "FREQ=MONTHLY;BYMONTHDAY=25" or "FREQ=WEEKLY;INTERVAL=2".
+// Weekly rules repeat on the weekday of the first occurrence. A month day
+// past the end of a shorter month falls on its last day.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByMonthDay int
+	ByMonth    int
+}
+
+// ParseRule reads a rule such as "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=25",
+// with or without the "RRULE:" prefix.
+func ParseRule(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = value
+		case "INTERVAL":
+			r.Interval, err = number(key, value, 1, 1000)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = number(key, value, 1, 31)
+		case "BYMONTH":
+			r.ByMonth, err = number(key, value, 1, 12)
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	switch r.Freq {
+	case Daily, Weekly:
+		if r.ByMonthDay != 0 || r.ByMonth != 0 {
+			return Rule{}, fmt.Errorf("BYMONTHDAY and BYMONTH need FREQ=MONTHLY or YEARLY")
+		}
+	case Monthly:
+		if r.ByMonth != 0 {
+			return Rule{}, errors.New("BYMONTH needs FREQ=YEARLY")
+		}
+	case Yearly:
+	case "":
+		return Rule{}, errors.New("FREQ is required")
+	default:
+		return Rule{}, fmt.Errorf("FREQ must be one of DAILY, WEEKLY, MONTHLY, YEARLY")
+	}
+	return r, nil
+}
+
+func number(key, value string, min, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a number from %d to %d", key, min, max)
+	}
+	return n, nil
+}
+
+func (r Rule) String() string {
+	s := "FREQ=" + r.Freq
+	if r.Interval > 1 {
+		s += ";INTERVAL=" + strconv.Itoa(r.Interval)
+	}
+	if r.ByMonth != 0 {
+		s += ";BYMONTH=" + strconv.Itoa(r.ByMonth)
+	}
+	if r.ByMonthDay != 0 {
+		s += ";BYMONTHDAY=" + strconv.Itoa(r.ByMonthDay)
+	}
+	return s
+}
+
+// Anchor fills the month day and month a monthly or yearly rule repeats on
+// from the start date when the rule leaves them out, so every later
+// occurrence can be worked out from the one before.
+func (r Rule) Anchor(start time.Time) Rule {
+	switch r.Freq {
+	case Monthly:
+		if r.ByMonthDay == 0 {
+			r.ByMonthDay = start.Day()
+		}
+	case Yearly:
+		if r.ByMonth == 0 {
+			r.ByMonth = int(start.Month())
+		}
+		if r.ByMonthDay == 0 {
+			r.ByMonthDay = start.Day()
+		}
+	}
+	return r
+}
+
+// First returns the first occurrence on or after start of an anchored rule.
+func (r Rule) First(start time.Time) time.Time {
+	y, m, _ := start.Date()
+	var first time.Time
+	switch r.Freq {
+	case Monthly:
+		first = day(y, m, r.ByMonthDay)
+		if first.Before(start) {
+			first = day(y, m+1, r.ByMonthDay)
+		}
+	case Yearly:
+		first = day(y, time.Month(r.ByMonth), r.ByMonthDay)
+		if first.Before(start) {
+			first = day(y+1, time.Month(r.ByMonth), r.ByMonthDay)
+		}
+	default:
+		first = start
+	}
+	return first
+}
+
+// Next returns the occurrence after prev of an anchored rule.
+func (r Rule) Next(prev time.Time) time.Time {
+	y, m, _ := prev.Date()
+	switch r.Freq {
+	case Daily:
+		return prev.AddDate(0, 0, r.Interval)
+	case Weekly:
+		return prev.AddDate(0, 0, 7*r.Interval)
+	case Monthly:
+		return day(y, m+time.Month(r.Interval), r.ByMonthDay)
+	default:
+		return day(y+r.Interval, time.Month(r.ByMonth), r.ByMonthDay)
+	}
+}
+
+// day is the date d of month m, or the last day of the month when it is
+// shorter. Dates are kept as midnight UTC.
+func day(y int, m time.Month, d int) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
@@ -0,0 +1,60 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse(dateLayout, s)
+	return d
+}
+
+func TestParseRule(t *testing.T) {
+	t.Run("parse the parts of a rule", func(t *testing.T) {
+		r, err := ParseRule("RRULE:freq=monthly;interval=2;bymonthday=25")
+
+		assert.NoError(t, err)
+		assert.Equal(t, Rule{Freq: Monthly, Interval: 2, ByMonthDay: 25}, r)
+		assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=25", r.String())
+	})
+
+	for _, tc := range []struct{ rule, err string }{
+		{"", "FREQ is required"},
+		{"FREQ=HOURLY", "FREQ must be one of DAILY, WEEKLY, MONTHLY, YEARLY"},
+		{"FREQ=DAILY;COUNT=3", "unsupported rule part COUNT"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "BYMONTHDAY must be a number from 1 to 31"},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", "BYMONTHDAY and BYMONTH need FREQ=MONTHLY or YEARLY"},
+		{"FREQ=DAILY;INTERVAL", `invalid rule part "INTERVAL"`},
+	} {
+		t.Run("reject "+tc.rule, func(t *testing.T) {
+			_, err := ParseRule(tc.rule)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestRuleOccurrences(t *testing.T) {
+	occurrences := func(rule, start string, n int) []string {
+		r, err := ParseRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = r.Anchor(date(start))
+
+		var got []string
+		for d := r.First(date(start)); len(got) < n; d = r.Next(d) {
+			got = append(got, d.Format(dateLayout))
+		}
+		return got
+	}
+
+	assert.Equal(t, []string{"2024-05-30", "2024-06-02", "2024-06-05"}, occurrences("FREQ=DAILY;INTERVAL=3", "2024-05-30", 3))
+	assert.Equal(t, []string{"2024-05-15", "2024-05-29", "2024-06-12"}, occurrences("FREQ=WEEKLY;INTERVAL=2", "2024-05-15", 3))
+	assert.Equal(t, []string{"2024-01-31", "2024-02-29", "2024-03-31", "2024-04-30"}, occurrences("FREQ=MONTHLY", "2024-01-31", 4))
+	assert.Equal(t, []string{"2024-05-25", "2024-06-25"}, occurrences("FREQ=MONTHLY;BYMONTHDAY=25", "2024-05-01", 2))
+	assert.Equal(t, []string{"2024-06-25", "2024-07-25"}, occurrences("FREQ=MONTHLY;BYMONTHDAY=25", "2024-05-26", 2))
+	assert.Equal(t, []string{"2024-02-29", "2025-02-28", "2026-02-28"}, occurrences("FREQ=YEARLY", "2024-02-29", 3))
+}
@@ -0,0 +1,184 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"go.uber.org/zap"
+)
+
+// lockKey names the Postgres advisory lock held while occurrences are
+// written, so only one instance of the app runs a tick at a time.
+const lockKey int64 = 0x7265637572 // "recur"
+
+const (
+	lockStmt   = `SELECT pg_try_advisory_xact_lock($1);`
+	dueStmt    = `SELECT r.id, r.spender_id, r.amount, r.category, r.transaction_type, r.note, r.currency, r.rule, r.until, r.next_run, s.timezone FROM recurring r JOIN spender s ON s.id = r.spender_id WHERE r.next_run <= $1 ORDER BY r.id FOR UPDATE OF r;`
+	insertStmt = `INSERT INTO transaction (date, amount, category, transaction_type, note, spender_id, currency, recurring_id, occurrence) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (recurring_id, occurrence) DO NOTHING;`
+	nextStmt   = `UPDATE recurring SET next_run = $1 WHERE id = $2;`
+)
+
+// Scheduler writes the occurrences of recurring transactions that are due.
+type Scheduler struct {
+	db     *sql.DB
+	every  time.Duration
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+// DefaultInterval is used when NewScheduler is given an interval a ticker
+// cannot run on.
+const DefaultInterval = time.Minute
+
+func NewScheduler(db *sql.DB, every time.Duration, logger *zap.Logger) *Scheduler {
+	if every <= 0 {
+		every = DefaultInterval
+	}
+	return &Scheduler{db: db, every: every, logger: logger, now: time.Now}
+}
+
+// Run ticks right away and then on every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.every)
+	defer ticker.Stop()
+
+	s.logger.Info("recurring scheduler started", zap.Duration("every", s.every))
+	for {
+		if n, err := s.Tick(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("recurring scheduler error", zap.Error(err))
+		} else if n > 0 {
+			s.logger.Info("recurring transactions created", zap.Int("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("recurring scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type due struct {
+	id, spenderID                  int64
+	amount                         money.Amount
+	category, tranType, note, curr string
+	rule                           string
+	until                          sql.NullTime
+	nextRun                        time.Time
+	timezone                       string
+}
+
+// Tick writes every occurrence that is due by today in the timezone of its
+// spender and moves the templates on. It returns how many transactions it
+// created. A tick is skipped while another instance holds the lock, and
+// the unique occurrence of a template keeps a transaction from being written
+// twice even then.
+func (s *Scheduler) Tick(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, lockStmt, lockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	// no timezone is ahead of UTC by a whole day, so this is a superset
+	// of what is due, narrowed down per spender below
+	now := s.now()
+	templates, err := s.due(ctx, tx, now.UTC().AddDate(0, 0, 1).Format(dateLayout))
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, t := range templates {
+		n, err := s.materialise(ctx, tx, t, now)
+		if err != nil {
+			return 0, err
+		}
+		created += n
+	}
+	return created, tx.Commit()
+}
+
+func (s *Scheduler) due(ctx context.Context, tx *sql.Tx, by string) ([]due, error) {
+	rows, err := tx.QueryContext(ctx, dueStmt, by)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []due
+	for rows.Next() {
+		var t due
+		err := rows.Scan(&t.id, &t.spenderID, &t.amount, &t.category, &t.tranType, &t.note, &t.curr, &t.rule, &t.until, &t.nextRun, &t.timezone)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *Scheduler) materialise(ctx context.Context, tx *sql.Tx, t due, now time.Time) (int, error) {
+	rule, err := ParseRule(t.rule)
+	if err != nil {
+		// a rule that never parses would stay due and fail every tick, so
+		// the template is retired like one past its end
+		s.logger.Error("invalid recurring rule, template retired", zap.Int64("id", t.id), zap.String("rule", t.rule), zap.Error(err))
+		_, err := tx.ExecContext(ctx, nextStmt, nil, t.id)
+		return 0, err
+	}
+	loc, err := time.LoadLocation(t.timezone)
+	if err != nil {
+		return 0, err
+	}
+
+	today := civil(now.In(loc))
+	occ := civil(t.nextRun)
+	var until time.Time
+	if t.until.Valid {
+		until = civil(t.until.Time)
+	}
+	ended := func(d time.Time) bool { return t.until.Valid && d.After(until) }
+
+	created := 0
+	for ; !occ.After(today) && !ended(occ); occ = rule.Next(occ) {
+		date := time.Date(occ.Year(), occ.Month(), occ.Day(), 0, 0, 0, 0, loc)
+		res, err := tx.ExecContext(ctx, insertStmt, date, t.amount, t.category, t.tranType, t.note, t.spenderID, t.curr, t.id, occ.Format(dateLayout))
+		if err != nil {
+			return 0, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return 0, err
+		} else if n > 0 {
+			created++
+		}
+	}
+
+	if occ.Equal(civil(t.nextRun)) {
+		return 0, nil
+	}
+	var next any = occ.Format(dateLayout)
+	if ended(occ) {
+		next = nil
+	}
+	if _, err := tx.ExecContext(ctx, nextStmt, next, t.id); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// civil keeps only the calendar date of t, as midnight UTC.
+func civil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,111 @@
+package recurring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestSchedulerTick(t *testing.T) {
+	// 2024-06-25 01:00 in Bangkok, still the 24th in UTC
+	now := time.Date(2024, 6, 24, 18, 0, 0, 0, time.UTC)
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	dueColumns := []string{"id", "spender_id", "amount", "category", "transaction_type", "note", "currency", "rule", "until", "next_run", "timezone"}
+
+	newScheduler := func() (*Scheduler, sqlmock.Sqlmock, func()) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		s := NewScheduler(db, time.Minute, zap.NewNop())
+		s.now = func() time.Time { return now }
+		return s, mock, func() { db.Close() }
+	}
+
+	t.Run("write the occurrences due in the timezone of the spender", func(t *testing.T) {
+		s, mock, done := newScheduler()
+		defer done()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(lockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(dueStmt).WithArgs("2024-06-25").WillReturnRows(sqlmock.NewRows(dueColumns).
+			AddRow(1, 1, "30000.00", "Salary", "income", "", "THB", "FREQ=MONTHLY;BYMONTHDAY=25", nil, date("2024-05-25"), "Asia/Bangkok").
+			AddRow(2, 2, "15.00", "Music", "expense", "", "USD", "FREQ=MONTHLY;BYMONTHDAY=25", nil, date("2024-06-25"), "America/New_York"))
+		mock.ExpectExec(insertStmt).
+			WithArgs(time.Date(2024, 5, 25, 0, 0, 0, 0, bkk), money.MustParse("30000"), "Salary", "income", "", int64(1), "THB", int64(1), "2024-05-25").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(insertStmt).
+			WithArgs(time.Date(2024, 6, 25, 0, 0, 0, 0, bkk), money.MustParse("30000"), "Salary", "income", "", int64(1), "THB", int64(1), "2024-06-25").
+			WillReturnResult(sqlmock.NewResult(10, 1))
+		mock.ExpectExec(nextStmt).WithArgs("2024-07-25", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		n, err := s.Tick(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n, "the occurrence of May was written before")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a schedule past its end stops", func(t *testing.T) {
+		s, mock, done := newScheduler()
+		defer done()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(lockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(dueStmt).WithArgs("2024-06-25").WillReturnRows(sqlmock.NewRows(dueColumns).
+			AddRow(3, 1, "500.00", "Gym", "expense", "last one", "THB", "FREQ=WEEKLY", date("2024-06-20"), date("2024-06-17"), "Asia/Bangkok"))
+		mock.ExpectExec(insertStmt).
+			WithArgs(time.Date(2024, 6, 17, 0, 0, 0, 0, bkk), money.MustParse("500"), "Gym", "expense", "last one", int64(1), "THB", int64(3), "2024-06-17").
+			WillReturnResult(sqlmock.NewResult(11, 1))
+		mock.ExpectExec(nextStmt).WithArgs(nil, int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		n, err := s.Tick(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("retire a template whose rule does not parse", func(t *testing.T) {
+		s, mock, done := newScheduler()
+		defer done()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(lockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(dueStmt).WithArgs("2024-06-25").WillReturnRows(sqlmock.NewRows(dueColumns).
+			AddRow(4, 1, "99.00", "Cloud", "expense", "", "THB", "FREQ=HOURLY", nil, date("2024-06-01"), "Asia/Bangkok"))
+		mock.ExpectExec(nextStmt).WithArgs(nil, int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		n, err := s.Tick(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skip the tick while another instance holds the lock", func(t *testing.T) {
+		s, mock, done := newScheduler()
+		defer done()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(lockKey).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectRollback()
+
+		n, err := s.Tick(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestNewScheduler(t *testing.T) {
+	for _, every := range []time.Duration{0, -time.Second} {
+		assert.Equal(t, DefaultInterval, NewScheduler(nil, every, zap.NewNop()).every)
+	}
+}
@@ -0,0 +1,165 @@
+package spender
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/kkgo-software-engineering/workshop/mlog"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// What Delete does with the transactions of the spender, chosen with the
+// transactions query parameter.
+const (
+	// Reject refuses to delete a spender that still has transactions.
+	Reject = "reject"
+	// Cascade deletes the transactions along with the spender.
+	Cascade = "cascade"
+	// Reassign moves the transactions to the spender named by reassign_to.
+	Reassign = "reassign"
+)
+
+const (
+	lockStmt        = `SELECT version FROM spender WHERE id = $1 FOR UPDATE;`
+	countTransStmt  = `SELECT (SELECT count(*) FROM transaction WHERE spender_id = $1) + (SELECT count(*) FROM transaction_split WHERE spender_id = $1);`
+	existsStmt      = `SELECT EXISTS(SELECT 1 FROM spender WHERE id = $1);`
+	dTransfersStmt  = `DELETE FROM transfer WHERE id IN (SELECT transfer_id FROM transaction WHERE spender_id = $1);`
+	dTransStmt      = `DELETE FROM transaction WHERE spender_id = $1;`
+	unsplitStmt     = `UPDATE transaction_split SET spender_id = NULL WHERE spender_id = $1;`
+	reassignStmt    = `UPDATE transaction SET spender_id = $2, version = version + 1 WHERE spender_id = $1;`
+	reassignSplStmt = `UPDATE transaction_split SET spender_id = $2 WHERE spender_id = $1;`
+	dStmt           = `DELETE FROM spender WHERE id = $1;`
+)
+
+// Delete removes a spender. Its budgets and recurring transactions go with
+// it, its transactions are handled as the transactions query parameter
+// says, reject when it is not given.
+func (h handler) Delete(c echo.Context) error {
+	if !h.flag.EnableDeleteSpender {
+		return c.JSON(http.StatusForbidden, "delete spender feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	ifMatch, err := etag.IfMatch(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	policy := c.QueryParam("transactions")
+	if policy == "" {
+		policy = Reject
+	}
+	var target int
+	switch policy {
+	case Reject, Cascade:
+		if c.QueryParam("reassign_to") != "" {
+			return c.JSON(http.StatusBadRequest, Err{Message: "reassign_to needs transactions=reassign"})
+		}
+	case Reassign:
+		if target, err = strconv.Atoi(c.QueryParam("reassign_to")); err != nil || target == 0 {
+			return c.JSON(http.StatusBadRequest, Err{Message: "invalid query parameter: reassign_to"})
+		}
+		if target == id {
+			return validate.Fail(c, validate.Errors{{Field: "reassign_to", Message: "must differ from the deleted spender"}})
+		}
+	default:
+		return c.JSON(http.StatusBadRequest, Err{Message: "invalid query parameter: transactions"})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("begin transaction error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	// the row lock keeps new transactions from being written for the
+	// spender until it is gone
+	var version int64
+	err = tx.QueryRowContext(ctx, lockStmt, id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "spender not found"})
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if ifMatch != nil && *ifMatch != version {
+		return etag.PreconditionFailed(c, version)
+	}
+
+	switch policy {
+	case Reject:
+		var n int64
+		if err := tx.QueryRowContext(ctx, countTransStmt, id).Scan(&n); err != nil {
+			logger.Error("query row error", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		if n > 0 {
+			return c.JSON(http.StatusConflict, Err{Message: fmt.Sprintf("spender has %d transactions, delete them with transactions=cascade or move them with transactions=reassign", n)})
+		}
+	case Cascade:
+		err = cascade(ctx, tx, id)
+	case Reassign:
+		var found bool
+		if err := tx.QueryRowContext(ctx, existsStmt, target).Scan(&found); err != nil {
+			logger.Error("query row error", zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		if !found {
+			return validate.Fail(c, validate.Errors{{Field: "reassign_to", Message: "does not exist"}})
+		}
+		err = reassign(ctx, tx, id, target)
+	}
+	if err != nil {
+		logger.Error("exec error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	if _, err := tx.ExecContext(ctx, dStmt, id); err != nil {
+		logger.Error("exec error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("commit error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("delete successfully", zap.Int("id", id), zap.String("transactions", policy))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// cascade deletes the transactions of a spender. A transfer goes as a
+// whole, and the split lines it had on transactions of others fall back
+// to their owner.
+func cascade(ctx context.Context, tx *sql.Tx, id int) error {
+	for _, stmt := range []string{dTransfersStmt, dTransStmt, unsplitStmt} {
+		if _, err := tx.ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reassign moves the transactions and split lines of a spender to target.
+func reassign(ctx context.Context, tx *sql.Tx, id, target int) error {
+	for _, stmt := range []string{reassignStmt, reassignSplStmt} {
+		if _, err := tx.ExecContext(ctx, stmt, id, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
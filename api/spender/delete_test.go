@@ -0,0 +1,164 @@
+package spender
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteSpender(t *testing.T) {
+	newContext := func(query, ifMatch string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodDelete, "/?"+query, nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		return c, rec
+	}
+	enabled := config.FeatureFlag{EnableDeleteSpender: true}
+	locked := func(version int) *sqlmock.Rows { return sqlmock.NewRows([]string{"version"}).AddRow(version) }
+
+	t.Run("delete spender without transactions", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(locked(2))
+		mock.ExpectQuery(countTransStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(dStmt).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		c, rec := newContext("", `"2"`)
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete spender rejected while it has transactions", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(locked(2))
+		mock.ExpectQuery(countTransStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+		mock.ExpectRollback()
+
+		c, rec := newContext("transactions=reject", "")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "spender has 4 transactions")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete spender with its transactions", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(locked(2))
+		mock.ExpectExec(dTransfersStmt).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(dTransStmt).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(unsplitStmt).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(dStmt).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		c, rec := newContext("transactions=cascade", "")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete spender moving its transactions to another", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(locked(2))
+		mock.ExpectQuery(existsStmt).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec(reassignStmt).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(reassignSplStmt).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(dStmt).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		c, rec := newContext("transactions=reassign&reassign_to=2", "")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete spender fail when the new owner does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(locked(2))
+		mock.ExpectQuery(existsStmt).WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		c, rec := newContext("transactions=reassign&reassign_to=9", "")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `{"field":"reassign_to","message":"does not exist"}`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete spender fail when spender does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		c, rec := newContext("", "")
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("delete spender fail when version does not match", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(lockStmt).WithArgs(1).WillReturnRows(locked(3))
+		mock.ExpectRollback()
+
+		c, rec := newContext("", `"2"`)
+		err := New(enabled, db).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+	})
+
+	for _, query := range []string{"transactions=archive", "transactions=reassign", "transactions=cascade&reassign_to=2"} {
+		t.Run("delete spender fail on "+query, func(t *testing.T) {
+			c, rec := newContext(query, "")
+			err := New(enabled, nil).Delete(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+
+	t.Run("delete spender fail when feature toggle is disable", func(t *testing.T) {
+		c, rec := newContext("", "")
+		err := New(config.FeatureFlag{}, nil).Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,139 @@
+package spender
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/kkgo-software-engineering/workshop/mlog"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// patchColumns maps the members of a merge patch document onto columns,
+// every one of them is NOT NULL so none can be removed with a JSON null.
+var patchColumns = []struct {
+	name  string
+	field string
+	value func(Spender) any
+}{
+	{name: "name", field: "Name", value: func(sp Spender) any { return sp.Name }},
+	{name: "email", field: "Email", value: func(sp Spender) any { return sp.Email }},
+	{name: "currency", field: "Currency", value: func(sp Spender) any { return sp.Currency }},
+	{name: "timezone", field: "Timezone", value: func(sp Spender) any { return sp.Timezone }},
+}
+
+// Patch applies a JSON Merge Patch (RFC 7386) to a spender, only the
+// members present in the document are written.
+func (h handler) Patch(c echo.Context) error {
+	if !h.flag.EnableUpdateSpender {
+		return c.JSON(http.StatusForbidden, "update spender feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	ifMatch, err := etag.IfMatch(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid spender request"})
+	}
+	p, err := buildPatch(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err := validate.Partial(c, &p.sp, p.fields...); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	args := append(p.args, id, ifMatch)
+	query := fmt.Sprintf("UPDATE spender SET %s WHERE id = $%d AND ($%d::int IS NULL OR version = $%d) RETURNING %s, version;",
+		strings.Join(append(p.sets, "version = version + 1"), ", "), len(args)-1, len(args), len(args), columns)
+
+	var sp Spender
+	var version int64
+	err = h.db.QueryRowContext(ctx, query, args...).Scan(&sp.ID, &sp.Name, &sp.Email, &sp.Currency, &sp.Timezone, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h.missed(c, id, ifMatch)
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	logger.Info("patch successfully", zap.Int64("id", sp.ID))
+	etag.Set(c, version)
+	return c.JSON(http.StatusOK, sp)
+}
+
+type patch struct {
+	sets []string
+	args []any
+	// sp holds the decoded members and fields names those to validate
+	sp     Spender
+	fields []string
+}
+
+// buildPatch turns a merge patch document into SET assignments and their
+// arguments. The document is decoded into a Spender first so the members
+// are type checked exactly like the body of Create.
+func buildPatch(body []byte) (patch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return patch{}, errors.New("Invalid spender request")
+	}
+	var p patch
+	if err := json.Unmarshal(body, &p.sp); err != nil {
+		return patch{}, errors.New("Invalid spender request")
+	}
+	p.sp.Currency = strings.ToUpper(p.sp.Currency)
+	p.sp.Email = normalizeEmail(p.sp.Email)
+	// an empty currency or timezone means the default on Create, which a
+	// patch cannot restore
+	for _, name := range []string{"currency", "timezone"} {
+		if raw, ok := members[name]; ok && bytes.Equal(bytes.TrimSpace(raw), []byte(`""`)) {
+			return patch{}, fmt.Errorf("%s cannot be removed", name)
+		}
+	}
+
+	known := map[string]bool{}
+	for _, col := range patchColumns {
+		known[col.name] = true
+		raw, ok := members[col.name]
+		if !ok {
+			continue
+		}
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			return patch{}, fmt.Errorf("%s cannot be removed", col.name)
+		}
+		p.args = append(p.args, col.value(p.sp))
+		p.sets = append(p.sets, fmt.Sprintf("%s = $%d", col.name, len(p.args)))
+		p.fields = append(p.fields, col.field)
+	}
+
+	for name := range members {
+		if !known[name] {
+			return patch{}, fmt.Errorf("unknown field: %s", name)
+		}
+	}
+	if len(p.sets) == 0 {
+		return patch{}, errors.New("no fields to update")
+	}
+
+	return p, nil
+}
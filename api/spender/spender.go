@@ -2,18 +2,53 @@ package spender
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
 	"github.com/kkgo-software-engineering/workshop/mlog"
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
 type Spender struct {
 	ID    int64  `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name  string `json:"name" validate:"required,max=255"`
+	Email string `json:"email" validate:"required,email,max=255"`
+	// Currency is the home currency summaries are reported in.
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	// Timezone is the IANA zone summaries are bucketed in.
+	Timezone string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+// DefaultTimezone is used when a new spender does not name one.
+const DefaultTimezone = "Asia/Bangkok"
+
+// normalize fills in the defaults of a spender written without them. Emails
+// are kept in lower case, they are unique regardless of case.
+func (sp *Spender) normalize() {
+	sp.Email = normalizeEmail(sp.Email)
+	sp.Currency = strings.ToUpper(sp.Currency)
+	if sp.Currency == "" {
+		sp.Currency = money.DefaultCurrency
+	}
+	if sp.Timezone == "" {
+		sp.Timezone = DefaultTimezone
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+type Err struct {
+	Message string `json:"message"`
 }
 
 type handler struct {
@@ -26,7 +61,13 @@ func New(cfg config.FeatureFlag, db *sql.DB) *handler {
 }
 
 const (
-	cStmt = `INSERT INTO spender (name, email) VALUES ($1, $2) RETURNING id;`
+	columns     = `id, name, email, currency, timezone`
+	cStmt       = `INSERT INTO spender (name, email, currency, timezone) VALUES ($1, $2, $3, $4) RETURNING id, version;`
+	getStmt     = `SELECT ` + columns + `, version FROM spender WHERE id = $1;`
+	uStmt       = `UPDATE spender SET name = $1, email = $2, currency = $3, timezone = $4, version = version + 1 WHERE id = $5 AND ($6::int IS NULL OR version = $6) RETURNING version;`
+	versionStmt = `SELECT version FROM spender WHERE id = $1;`
+
+	errUnique = "23505"
 )
 
 func (h handler) Create(c echo.Context) error {
@@ -42,16 +83,20 @@ func (h handler) Create(c echo.Context) error {
 		logger.Error("bad request body", zap.Error(err))
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
+	sp.normalize()
+	if err := validate.Request(c, &sp); err != nil {
+		return validate.Fail(c, err)
+	}
 
-	var lastInsertId int64
-	err = h.db.QueryRowContext(ctx, cStmt, sp.Name, sp.Email).Scan(&lastInsertId)
+	var lastInsertId, version int64
+	err = h.db.QueryRowContext(ctx, cStmt, sp.Name, sp.Email, sp.Currency, sp.Timezone).Scan(&lastInsertId, &version)
 	if err != nil {
-		logger.Error("query row error", zap.Error(err))
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return writeError(c, err)
 	}
 
 	logger.Info("create successfully", zap.Int64("id", lastInsertId))
 	sp.ID = lastInsertId
+	etag.Set(c, version)
 	return c.JSON(http.StatusCreated, sp)
 }
 
@@ -59,7 +104,7 @@ func (h handler) GetAll(c echo.Context) error {
 	logger := mlog.L(c)
 	ctx := c.Request().Context()
 
-	rows, err := h.db.QueryContext(ctx, `SELECT id, name, email FROM spender`)
+	rows, err := h.db.QueryContext(ctx, `SELECT `+columns+` FROM spender`)
 	if err != nil {
 		logger.Error("query error", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, err.Error())
@@ -69,7 +114,7 @@ func (h handler) GetAll(c echo.Context) error {
 	var sps []Spender
 	for rows.Next() {
 		var sp Spender
-		err := rows.Scan(&sp.ID, &sp.Name, &sp.Email)
+		err := rows.Scan(&sp.ID, &sp.Name, &sp.Email, &sp.Currency, &sp.Timezone)
 		if err != nil {
 			logger.Error("scan error", zap.Error(err))
 			return c.JSON(http.StatusInternalServerError, err.Error())
@@ -79,3 +124,101 @@ func (h handler) GetAll(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, sps)
 }
+
+func (h handler) GetByID(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	var sp Spender
+	var version int64
+	err = h.db.QueryRowContext(ctx, getStmt, id).Scan(&sp.ID, &sp.Name, &sp.Email, &sp.Currency, &sp.Timezone, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "spender not found"})
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	etag.Set(c, version)
+	return c.JSON(http.StatusOK, sp)
+}
+
+// Update replaces a spender, the currency and timezone left out fall back
+// to their defaults like on Create.
+func (h handler) Update(c echo.Context) error {
+	if !h.flag.EnableUpdateSpender {
+		return c.JSON(http.StatusForbidden, "update spender feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	ifMatch, err := etag.IfMatch(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	var sp Spender
+	if err := c.Bind(&sp); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid spender request"})
+	}
+	sp.normalize()
+	if err := validate.Request(c, &sp); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	var version int64
+	err = h.db.QueryRowContext(ctx, uStmt, sp.Name, sp.Email, sp.Currency, sp.Timezone, id, ifMatch).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h.missed(c, id, ifMatch)
+	}
+	if err != nil {
+		return writeError(c, err)
+	}
+
+	logger.Info("update successfully", zap.Int("id", id))
+	sp.ID = int64(id)
+	etag.Set(c, version)
+	return c.JSON(http.StatusOK, sp)
+}
+
+// missed answers a conditional write that touched no row, either the
+// spender does not exist or somebody else changed it first.
+func (h handler) missed(c echo.Context, id int, ifMatch *int64) error {
+	if ifMatch == nil {
+		return c.JSON(http.StatusNotFound, Err{Message: "spender not found"})
+	}
+
+	var current int64
+	err := h.db.QueryRowContext(c.Request().Context(), versionStmt, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "spender not found"})
+	}
+	if err != nil {
+		mlog.L(c).Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return etag.PreconditionFailed(c, current)
+}
+
+// writeError answers a failed write, a taken email is a conflict rather
+// than a server error.
+func writeError(c echo.Context, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == errUnique {
+		return c.JSON(http.StatusConflict, Err{Message: "email is already taken"})
+	}
+	mlog.L(c).Error("query row error", zap.Error(err))
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
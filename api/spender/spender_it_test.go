@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
 	"github.com/KKGo-Software-engineering/workshop-summer/migration"
 	"github.com/labstack/echo/v4"
 	_ "github.com/lib/pq"
@@ -28,6 +29,7 @@ func TestCreateSpenderIT(t *testing.T) {
 		h := New(config.FeatureFlag{EnableCreateSpender: true}, sql)
 		e := echo.New()
 		defer e.Close()
+		e.Validator = validate.New(sql)
 
 		e.POST("/spenders", h.Create)
 
@@ -8,7 +8,9 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,16 +28,103 @@ func TestCreateSpender(t *testing.T) {
 		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		defer db.Close()
 
-		row := sqlmock.NewRows([]string{"id"}).AddRow(1)
-		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok").WillReturnRows(row)
+		row := sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 1)
+		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").WillReturnRows(row)
 		cfg := config.FeatureFlag{EnableCreateSpender: true}
+		e.Validator = validate.New(db)
 
 		h := New(cfg, db)
 		err := h.Create(c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusCreated, rec.Code)
-		assert.JSONEq(t, `{"id": 1, "name": "HongJot", "email": "hong@jot.ok"}`, rec.Body.String())
+		assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"id": 1, "name": "HongJot", "email": "hong@jot.ok", "currency": "THB", "timezone": "Asia/Bangkok"}`, rec.Body.String())
+	})
+
+	t.Run("create spender with home currency given in lower case", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "HongJot", "email": "hong@jot.ok", "currency": "jpy"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok", "JPY", "Asia/Bangkok").WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 1))
+
+		h := New(config.FeatureFlag{EnableCreateSpender: true}, db)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"id": 1, "name": "HongJot", "email": "hong@jot.ok", "currency": "JPY", "timezone": "Asia/Bangkok"}`, rec.Body.String())
+	})
+
+	t.Run("create spender with email given in mixed case", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "HongJot", "email": " Hong@Jot.OK "}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 1))
+
+		h := New(config.FeatureFlag{EnableCreateSpender: true}, db)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"email":"hong@jot.ok"`)
+	})
+
+	t.Run("create spender failed when email is taken", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "HongJot", "email": "hong@jot.ok"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").WillReturnError(&pq.Error{Code: errUnique})
+
+		h := New(config.FeatureFlag{EnableCreateSpender: true}, db)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.JSONEq(t, `{"message": "email is already taken"}`, rec.Body.String())
+	})
+
+	t.Run("create spender failed when currency and timezone are unknown", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "HongJot", "email": "hong@jot.ok", "currency": "baht", "timezone": "Mars/Olympus"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{EnableCreateSpender: true}, nil)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"currency"`)
+		assert.Contains(t, rec.Body.String(), `"field":"timezone"`)
 	})
 
 	t.Run("create spender failed when feature toggle is disable", func(t *testing.T) {
@@ -74,6 +163,28 @@ func TestCreateSpender(t *testing.T) {
 		assert.Contains(t, rec.Body.String(), "invalid character")
 	})
 
+	t.Run("create spender failed when name and email are invalid", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "", "email": "hong-at-jot"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		cfg := config.FeatureFlag{EnableCreateSpender: true}
+
+		h := New(cfg, nil)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message":"validation failed","errors":[
+			{"field":"name","message":"is required"},
+			{"field":"email","message":"must be a valid email address"}
+		]}`, rec.Body.String())
+	})
+
 	t.Run("create spender failed on database (feature toggle is enable) ", func(t *testing.T) {
 		e := echo.New()
 		defer e.Close()
@@ -86,8 +197,9 @@ func TestCreateSpender(t *testing.T) {
 		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		defer db.Close()
 
-		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok").WillReturnError(assert.AnError)
+		mock.ExpectQuery(cStmt).WithArgs("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").WillReturnError(assert.AnError)
 		cfg := config.FeatureFlag{EnableCreateSpender: true}
+		e.Validator = validate.New(db)
 
 		h := New(cfg, db)
 		err := h.Create(c)
@@ -109,18 +221,18 @@ func TestGetAllSpender(t *testing.T) {
 		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		defer db.Close()
 
-		rows := sqlmock.NewRows([]string{"id", "name", "email"}).
-			AddRow(1, "HongJot", "hong@jot.ok").
-			AddRow(2, "JotHong", "jot@jot.ok")
-		mock.ExpectQuery(`SELECT id, name, email FROM spender`).WillReturnRows(rows)
+		rows := sqlmock.NewRows([]string{"id", "name", "email", "currency", "timezone"}).
+			AddRow(1, "HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").
+			AddRow(2, "JotHong", "jot@jot.ok", "JPY", "Asia/Tokyo")
+		mock.ExpectQuery(`SELECT id, name, email, currency, timezone FROM spender`).WillReturnRows(rows)
 
 		h := New(config.FeatureFlag{}, db)
 		err := h.GetAll(c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
-		assert.JSONEq(t, `[{"id": 1, "name": "HongJot", "email": "hong@jot.ok"},
-		{"id": 2, "name": "JotHong", "email": "jot@jot.ok"}]`, rec.Body.String())
+		assert.JSONEq(t, `[{"id": 1, "name": "HongJot", "email": "hong@jot.ok", "currency": "THB", "timezone": "Asia/Bangkok"},
+		{"id": 2, "name": "JotHong", "email": "jot@jot.ok", "currency": "JPY", "timezone": "Asia/Tokyo"}]`, rec.Body.String())
 	})
 
 	t.Run("get all spender failed on database", func(t *testing.T) {
@@ -134,7 +246,7 @@ func TestGetAllSpender(t *testing.T) {
 		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		defer db.Close()
 
-		mock.ExpectQuery(`SELECT id, name, email FROM spender`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT id, name, email, currency, timezone FROM spender`).WillReturnError(assert.AnError)
 
 		h := New(config.FeatureFlag{}, db)
 		err := h.GetAll(c)
@@ -143,3 +255,163 @@ func TestGetAllSpender(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
+
+func TestGetSpenderByID(t *testing.T) {
+	newContext := func(id string) (echo.Context, *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		return c, rec
+	}
+
+	t.Run("get spender successfully", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(getStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "currency", "timezone", "version"}).
+			AddRow(1, "HongJot", "hong@jot.ok", "THB", "Asia/Bangkok", 3))
+
+		c, rec := newContext("1")
+		err := New(config.FeatureFlag{}, db).GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"id": 1, "name": "HongJot", "email": "hong@jot.ok", "currency": "THB", "timezone": "Asia/Bangkok"}`, rec.Body.String())
+	})
+
+	t.Run("get spender fail when spender does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(getStmt).WithArgs(9).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "currency", "timezone", "version"}))
+
+		c, rec := newContext("9")
+		err := New(config.FeatureFlag{}, db).GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"message": "spender not found"}`, rec.Body.String())
+	})
+
+	t.Run("get spender fail when id is invalid", func(t *testing.T) {
+		c, rec := newContext("abc")
+		err := New(config.FeatureFlag{}, nil).GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestUpdateSpender(t *testing.T) {
+	newContext := func(method, body, ifMatch string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		return c, rec
+	}
+	enabled := config.FeatureFlag{EnableUpdateSpender: true}
+
+	t.Run("update spender successfully", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(uStmt).WithArgs("HongJot", "hong@jot.ok", "JPY", "Asia/Bangkok", 1, int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+		c, rec := newContext(http.MethodPut, `{"name": "HongJot", "email": "hong@jot.ok", "currency": "jpy"}`, `"2"`)
+		err := New(enabled, db).Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"id": 1, "name": "HongJot", "email": "hong@jot.ok", "currency": "JPY", "timezone": "Asia/Bangkok"}`, rec.Body.String())
+	})
+
+	t.Run("update spender fail when version does not match", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(uStmt).WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectQuery(versionStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(5))
+
+		c, rec := newContext(http.MethodPut, `{"name": "HongJot", "email": "hong@jot.ok"}`, `"2"`)
+		err := New(enabled, db).Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, `"5"`, rec.Header().Get("ETag"))
+	})
+
+	t.Run("update spender fail when spender does not exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(uStmt).WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+		c, rec := newContext(http.MethodPut, `{"name": "HongJot", "email": "hong@jot.ok"}`, "")
+		err := New(enabled, db).Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("update spender fail when feature toggle is disable", func(t *testing.T) {
+		c, rec := newContext(http.MethodPut, `{}`, "")
+		err := New(config.FeatureFlag{}, nil).Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("patch spender writes only the members given", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(`UPDATE spender SET timezone = $1, version = version + 1 WHERE id = $2 AND ($3::int IS NULL OR version = $3) RETURNING id, name, email, currency, timezone, version;`).
+			WithArgs("Asia/Tokyo", 1, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "currency", "timezone", "version"}).
+				AddRow(1, "HongJot", "hong@jot.ok", "THB", "Asia/Tokyo", 2))
+
+		c, rec := newContext(http.MethodPatch, `{"timezone": "Asia/Tokyo"}`, "")
+		err := New(enabled, db).Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id": 1, "name": "HongJot", "email": "hong@jot.ok", "currency": "THB", "timezone": "Asia/Tokyo"}`, rec.Body.String())
+	})
+
+	t.Run("patch spender fail when email is taken", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(`UPDATE spender SET email = $1, version = version + 1 WHERE id = $2 AND ($3::int IS NULL OR version = $3) RETURNING id, name, email, currency, timezone, version;`).
+			WithArgs("hong@jot.ok", 1, nil).
+			WillReturnError(&pq.Error{Code: errUnique})
+
+		c, rec := newContext(http.MethodPatch, `{"email": "Hong@Jot.OK"}`, "")
+		err := New(enabled, db).Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	for body, message := range map[string]string{
+		`{"email": null}`:  "email cannot be removed",
+		`{"currency": ""}`: "currency cannot be removed",
+		`{"id": 2}`:        "unknown field: id",
+		`{}`:               "no fields to update",
+	} {
+		t.Run("patch spender fail on "+body, func(t *testing.T) {
+			c, rec := newContext(http.MethodPatch, body, "")
+			err := New(enabled, nil).Patch(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), message)
+		})
+	}
+}
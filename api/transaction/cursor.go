@@ -0,0 +1,40 @@
+package transaction
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// Cursor is the position of a row in the keyset ordering (date DESC, id DESC).
+// Clients only ever see it as an opaque token.
+type Cursor struct {
+	Date time.Time `json:"d"`
+	ID   int64     `json:"i"`
+	// Prev marks a token that pages backwards, towards newer rows.
+	Prev bool `json:"p,omitempty"`
+}
+
+type CursorPagination struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	PerPage    int    `json:"per_page"`
+}
+
+func (cur Cursor) Encode() string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, queryError{"cursor"}
+	}
+
+	var cur Cursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.ID == 0 {
+		return Cursor{}, queryError{"cursor"}
+	}
+	return cur, nil
+}
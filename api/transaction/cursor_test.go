@@ -0,0 +1,25 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("encoded cursor decodes to the same position", func(t *testing.T) {
+		cur := Cursor{Date: time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC), ID: 42, Prev: true}
+
+		got, err := decodeCursor(cur.Encode())
+
+		assert.NoError(t, err)
+		assert.Equal(t, cur, got)
+	})
+
+	t.Run("tampered cursor returns error", func(t *testing.T) {
+		_, err := decodeCursor("not-a-cursor")
+
+		assert.EqualError(t, err, "invalid query parameter: cursor")
+	})
+}
@@ -1,13 +1,18 @@
 package transaction
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
@@ -16,17 +21,56 @@ type Err struct {
 	Message string `json:"message"`
 }
 type handler struct {
-	flag config.FeatureFlag
-	db   *sql.DB
+	flag    config.FeatureFlag
+	db      *sql.DB
+	alerter Alerter
 }
 
 func New(cfg config.FeatureFlag, db *sql.DB) *handler {
-	return &handler{cfg, db}
+	return &handler{flag: cfg, db: db}
 }
 
+// Alerter is told about every expense written, so budgets can be checked
+// against the new spend.
+type Alerter interface {
+	Spent(ctx context.Context, spenderID int64, category string, date time.Time)
+}
+
+// WithAlerter makes the handler report expenses to a.
+func (h *handler) WithAlerter(a Alerter) *handler {
+	h.alerter = a
+	return h
+}
+
+// spent reports an expense to the alerter, every split line on its own.
+func (h handler) spent(ctx context.Context, tR TransactionResponse) {
+	if h.alerter == nil || tR.TransactionType != "expense" || tR.SpenderID == nil || tR.Date == nil {
+		return
+	}
+	if len(tR.Splits) == 0 {
+		h.alerter.Spent(ctx, *tR.SpenderID, tR.Category, *tR.Date)
+		return
+	}
+	for _, s := range tR.Splits {
+		spenderID := *tR.SpenderID
+		if s.SpenderID != nil {
+			spenderID = *s.SpenderID
+		}
+		h.alerter.Spent(ctx, spenderID, s.Category, *tR.Date)
+	}
+}
+
+// currencyExpr takes the currency given in $8, or the home currency of the
+// spender in $7 when it is empty.
+const currencyExpr = `COALESCE(NULLIF($8, ''), (SELECT currency FROM spender WHERE id = $7))`
+
 const (
-	cStmt = `INSERT INTO transaction (date, amount, category, transaction_type, note, image_url, spender_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`
-	uStmt = `UPDATE transaction SET date = $1, amount = $2, category = $3, transaction_type = $4, note = $5, image_url = $6, spender_id = $7 WHERE id = $8 RETURNING id;`
+	cStmt         = `INSERT INTO transaction (date, amount, category, transaction_type, note, image_url, spender_id, currency) VALUES ($1, $2, $3, $4, $5, $6, $7, ` + currencyExpr + `) RETURNING id, currency;`
+	selectColumns = `id, date, amount, category, transaction_type, note, image_url, spender_id, currency`
+	selectStmt    = `SELECT ` + selectColumns + ` FROM transaction`
+	getStmt       = `SELECT ` + selectColumns + `, version FROM transaction WHERE id = $1 AND deleted_at IS NULL;`
+	countStmt     = `SELECT COUNT(*) FROM transaction`
+	uStmt         = `UPDATE transaction SET date = $1, amount = $2, category = $3, transaction_type = $4, note = $5, image_url = $6, spender_id = $7, currency = ` + currencyExpr + `, version = version + 1 WHERE id = $9 AND deleted_at IS NULL AND ($10::int IS NULL OR version = $10) RETURNING id, version, currency;`
 )
 
 func (h handler) Create(c echo.Context) error {
@@ -41,24 +85,49 @@ func (h handler) Create(c echo.Context) error {
 	if err := c.Bind(&tranReq); err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid transaction request"})
 	}
+	tranReq.Currency = strings.ToUpper(tranReq.Currency)
+	tranReq.Tags = normalizeTags(tranReq.Tags)
+	if err := validate.Request(c, &tranReq); err != nil {
+		return validate.Fail(c, err)
+	}
+	if err := checkSplits(tranReq.Amount, tranReq.Splits); err != nil {
+		return validate.Fail(c, err)
+	}
 
-	//validate spender id from spender table
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("begin transaction error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
 
 	//create transaction
 	var lastInsertId int64
-	err := h.db.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		cStmt,
-		tranReq.Date, tranReq.Amount, tranReq.Category, tranReq.TransactionType, tranReq.Note, tranReq.ImageUrl, tranReq.SpenderID,
-	).Scan(&lastInsertId)
+		tranReq.Date, tranReq.Amount, tranReq.Category, tranReq.TransactionType, tranReq.Note, tranReq.ImageUrl, tranReq.SpenderID, tranReq.Currency,
+	).Scan(&lastInsertId, &tranReq.Currency)
 
 	if err != nil {
 		logger.Error("query row error", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
+	if err := insertSplits(ctx, tx, lastInsertId, tranReq.Splits); err != nil {
+		logger.Error("insert splits error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if err := insertTags(ctx, tx, lastInsertId, tranReq.Tags); err != nil {
+		logger.Error("insert tags error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("commit error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
 
 	logger.Info("create successfully", zap.Int64("id", lastInsertId))
-	return c.JSON(http.StatusCreated, TransactionResponse{
+	tR := TransactionResponse{
 		ID:              lastInsertId,
 		Date:            &tranReq.Date,
 		Amount:          tranReq.Amount,
@@ -66,92 +135,83 @@ func (h handler) Create(c echo.Context) error {
 		TransactionType: tranReq.TransactionType,
 		Note:            tranReq.Note,
 		ImageUrl:        tranReq.ImageUrl,
-	})
+		SpenderID:       &tranReq.SpenderID,
+		Currency:        tranReq.Currency,
+		Splits:          tranReq.Splits,
+		Tags:            tranReq.Tags,
+	}
+	h.spent(ctx, tR)
+	return c.JSON(http.StatusCreated, tR)
 }
 
 func (h handler) GetAll(c echo.Context) error {
 	logger := mlog.L(c)
-	ctx := c.Request().Context()
-
-	// page, err := strconv.Atoi(c.URL.Query().Get("page"))
-	// if err != nil || page < 0 {
-	// 	page = 0 // Default to the first page if page parameter is invalid
-	// }
 
-	// pageSize, err := strconv.Atoi(c.URL.Query().Get("pageSize"))
-	// if err != nil || pageSize <= 0 {
-	// 	pageSize = 10 // Default page size
-	// }
-
-	rows, err := h.db.QueryContext(ctx, `SELECT id, date, amount, category, transaction_type, note, image_url FROM transaction`)
+	resp, err := h.list(c, 0)
+	if errors.As(err, &queryError{}) {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
 	if err != nil {
 		logger.Error("query error", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
-	defer rows.Close()
 
-	var tRs []TransactionResponse
-	for rows.Next() {
-		var tR TransactionResponse
-		err := rows.Scan(&tR.ID, &tR.Date, &tR.Amount, &tR.Category, &tR.TransactionType, &tR.Note, &tR.ImageUrl)
-		if err != nil {
-			logger.Error("scan error", zap.Error(err))
-			return c.JSON(http.StatusInternalServerError, err.Error())
-		}
-		tRs = append(tRs, tR)
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h handler) GetByID(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
 	}
 
-	return c.JSON(http.StatusOK, echo.Map{"transactions": tRs})
-}
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
 
-func GetByExpenseId() {
+	var tR TransactionResponse
+	var version int64
+	err = h.db.QueryRowContext(ctx, getStmt, id).Scan(append(tR.fields(), &version)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "transaction not found"})
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if tR.Splits, err = getSplits(ctx, h.db, tR.ID); err != nil {
+		logger.Error("query splits error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if tR.Tags, err = getTags(ctx, h.db, tR.ID); err != nil {
+		logger.Error("query tags error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
 
+	etag.Set(c, version)
+	return c.JSON(http.StatusOK, tR)
 }
 
 func (h *handler) GetTransactionById(c echo.Context) error {
 	// Retrieve spenderID as a string and convert to integer
 	spenderIDStr := c.Param("id")
 	spenderID, err := strconv.Atoi(spenderIDStr)
-	ctx := c.Request().Context()
 
-	// fmt.Print("id is ", spenderIDStr)
 	if err != nil {
 		// Return an error if conversion fails
 		c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid spender ID"})
 		return err
 	}
 
-	var transactions []TransactionResponse
-
-	// Use the integer spenderID in the SQL query
-	rows, err := h.db.QueryContext(ctx, `
-        SELECT id, date, amount, category, transaction_type, note, image_url
-        FROM transaction
-        WHERE spender_id = $1`, spenderID)
+	resp, err := h.list(c, int64(spenderID))
+	if errors.As(err, &queryError{}) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"error": err.Error()})
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, echo.Map{"error": "Database error"})
 		return err
 	}
-	defer rows.Close()
-	fmt.Println("Transsation ", rows)
 
-	for rows.Next() {
-		var t TransactionResponse
-		if err := rows.Scan(&t.ID, &t.Date, &t.Amount, &t.Category, &t.TransactionType, &t.Note, &t.ImageUrl); err != nil {
-			c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error scanning database results"})
-			fmt.Println("print t ", t)
-			return err
-
-		}
-		transactions = append(transactions, t)
-	}
-
-	if err = rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error iterating database results"})
-		return err
-	}
-
-	return c.JSON(http.StatusOK, echo.Map{"transactions": transactions})
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (h handler) Update(c echo.Context) error {
@@ -169,21 +229,52 @@ func (h handler) Update(c echo.Context) error {
 	logger := mlog.L(c)
 	ctx := c.Request().Context()
 
+	ifMatch, err := etag.IfMatch(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
 	var tranReq TransactionRequest
 	if err := c.Bind(&tranReq); err != nil {
 		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid transaction request"})
 	}
-	var lastInsertId int64
-	err = h.db.QueryRowContext(ctx, uStmt,
-		tranReq.Date, tranReq.Amount, tranReq.Category, tranReq.TransactionType, tranReq.Note, tranReq.ImageUrl, tranReq.SpenderID,
-	).Scan(&lastInsertId)
+	tranReq.Currency = strings.ToUpper(tranReq.Currency)
+	tranReq.Tags = normalizeTags(tranReq.Tags)
+	if err := validate.Request(c, &tranReq); err != nil {
+		return validate.Fail(c, err)
+	}
+	if err := checkSplits(tranReq.Amount, tranReq.Splits); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("begin transaction error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	var lastInsertId, version int64
+	err = tx.QueryRowContext(ctx, uStmt,
+		tranReq.Date, tranReq.Amount, tranReq.Category, tranReq.TransactionType, tranReq.Note, tranReq.ImageUrl, tranReq.SpenderID, tranReq.Currency, id, ifMatch,
+	).Scan(&lastInsertId, &version, &tranReq.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h.missed(c, id, ifMatch)
+	}
 	if err != nil {
 		logger.Error("query row error", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
-
-	logger.Info("update successfully", zap.Int64("id", lastInsertId))
-	return c.JSON(http.StatusOK, TransactionResponse{
+	// a PUT replaces the whole transaction, splits left out are removed
+	if err := replaceSplits(ctx, tx, lastInsertId, tranReq.Splits); err != nil {
+		logger.Error("replace splits error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if err := replaceTags(ctx, tx, lastInsertId, tranReq.Tags); err != nil {
+		logger.Error("replace tags error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	tR := TransactionResponse{
 		ID:              lastInsertId,
 		Date:            &tranReq.Date,
 		Amount:          tranReq.Amount,
@@ -191,5 +282,21 @@ func (h handler) Update(c echo.Context) error {
 		TransactionType: tranReq.TransactionType,
 		Note:            tranReq.Note,
 		ImageUrl:        tranReq.ImageUrl,
-	})
+		SpenderID:       &tranReq.SpenderID,
+		Currency:        tranReq.Currency,
+		Splits:          tranReq.Splits,
+		Tags:            tranReq.Tags,
+	}
+	if err := mirror(ctx, tx, tR); err != nil {
+		return failMirror(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("commit error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("update successfully", zap.Int64("id", lastInsertId))
+	etag.Set(c, version)
+	h.spent(ctx, tR)
+	return c.JSON(http.StatusOK, tR)
 }
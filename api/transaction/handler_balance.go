@@ -0,0 +1,88 @@
+package transaction
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type CurrencyBalance struct {
+	Currency string       `json:"currency"`
+	Balance  money.Amount `json:"balance"`
+}
+
+// BalanceResponse is the balance of a spender at the end of AsOf, in the
+// home currency, with the balance kept in every currency next to it.
+type BalanceResponse struct {
+	SpenderID  int64             `json:"spender_id"`
+	AsOf       string            `json:"as_of"`
+	Currency   string            `json:"currency"`
+	Balance    money.Amount      `json:"balance"`
+	ByCurrency []CurrencyBalance `json:"by_currency"`
+}
+
+// GetSpenderBalance returns the balance after every transaction up to and
+// including the as_of day, today when it is not given. Currencies are
+// converted at the rate known on that day.
+func (h handler) GetSpenderBalance(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid spender id")
+	}
+
+	ctx := c.Request().Context()
+
+	home, _, loc, err := h.getSpenderZone(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, "spender not found")
+	}
+	if err != nil {
+		mlog.L(c).Error("query spender error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderBalance error")
+	}
+
+	asOf := time.Now().In(loc)
+	if v := c.QueryParam("as_of"); v != "" {
+		if asOf, err = time.ParseInLocation(dateLayout, v, loc); err != nil {
+			return c.JSON(http.StatusBadRequest, Err{Message: queryError{"as_of"}.Error()})
+		}
+	}
+	p := Period{loc: loc}
+	end := p.day(asOf).AddDate(0, 0, 1)
+	p.To = &end
+
+	summaryTran, err := h.getSummaryTransaction(ctx, id, p)
+	if err != nil {
+		mlog.L(c).Error("query summary error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderBalance error")
+	}
+
+	summary, err := h.newConverter(ctx, home, asOf).summary(summaryTran)
+	if errors.Is(err, fx.ErrNoRate) {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+	}
+	if err != nil {
+		mlog.L(c).Error("convert balance error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderBalance error")
+	}
+
+	resp := BalanceResponse{
+		SpenderID:  int64(id),
+		AsOf:       asOf.Format(dateLayout),
+		Currency:   home,
+		Balance:    summary.Summary.CurrentBalance,
+		ByCurrency: []CurrencyBalance{},
+	}
+	for _, cs := range summary.ByCurrency {
+		resp.ByCurrency = append(resp.ByCurrency, CurrencyBalance{Currency: cs.Currency, Balance: cs.CurrentBalance})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,85 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSpenderBalance(t *testing.T) {
+	newContext := func(query string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/balance?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		return c, rec
+	}
+
+	t.Run("balance at the end of the as_of day", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		bkk, _ := time.LoadLocation("Asia/Bangkok")
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+		mock.ExpectQuery(summary_stmt).WithArgs(1, nil, time.Date(2024, 5, 2, 0, 0, 0, 0, bkk), false).
+			WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type", "currency"}).
+				AddRow("1000.00", "income", "THB").
+				AddRow("250.50", "expense", "THB").
+				AddRow("20.00", "expense", "USD"))
+		mock.ExpectQuery(`SELECT base, rate FROM fx_rate WHERE ((base = $1 AND quote = $2) OR (base = $2 AND quote = $1)) AND effective_date <= $3 ORDER BY effective_date DESC, base = $1 DESC LIMIT 1;`).
+			WithArgs("USD", "THB", "2024-05-01").
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("USD", "36.5"))
+
+		c, rec := newContext("as_of=2024-05-01")
+		err := handler{db: db}.GetSpenderBalance(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"spender_id": 1,
+			"as_of": "2024-05-01",
+			"currency": "THB",
+			"balance": 19.5,
+			"by_currency": [
+				{"currency": "THB", "balance": 749.5},
+				{"currency": "USD", "balance": -20}
+			]
+		}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no transactions yet is a zero balance", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+		mock.ExpectQuery(summary_stmt).WithArgs(1, nil, sqlmock.AnyArg(), false).
+			WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type", "currency"}))
+
+		c, rec := newContext("")
+		err := handler{db: db}.GetSpenderBalance(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"balance":0`)
+		assert.Contains(t, rec.Body.String(), `"by_currency":[]`)
+	})
+
+	t.Run("invalid as_of", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+
+		c, rec := newContext("as_of=May")
+		err := handler{db: db}.GetSpenderBalance(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"message": "invalid query parameter: as_of"}`, rec.Body.String())
+	})
+}
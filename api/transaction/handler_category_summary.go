@@ -0,0 +1,173 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type CategoryShare struct {
+	Category string       `json:"category"`
+	Total    money.Amount `json:"total"`
+	Count    int64        `json:"count"`
+	Percent  float64      `json:"percent"`
+}
+
+// CategoryBreakdown splits the total of one transaction type by category.
+type CategoryBreakdown struct {
+	Total      money.Amount    `json:"total"`
+	Count      int64           `json:"count"`
+	Categories []CategoryShare `json:"categories"`
+}
+
+type CategorySummaryResponse struct {
+	Currency string            `json:"currency"`
+	From     string            `json:"from,omitempty"`
+	To       string            `json:"to,omitempty"`
+	Income   CategoryBreakdown `json:"income"`
+	Expense  CategoryBreakdown `json:"expense"`
+}
+
+type categoryTotal struct {
+	Category string
+	Count    int64
+	SummaryTransaction
+}
+
+const (
+	categories_stmt = `SELECT category, transaction_type as tran_type, currency, sum(amount) as total_amount, count(*) as total_count FROM transaction_line WHERE spender_id = $1 AND deleted_at IS NULL` + periodCond + ` group by category, transaction_type, currency`
+)
+
+// GetSpenderCategorySummary answers "where did my money go": the totals,
+// counts and shares of every category, in the home currency of the spender.
+func (h handler) GetSpenderCategorySummary(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid spender id")
+	}
+
+	ctx := c.Request().Context()
+
+	home, _, loc, err := h.getSpenderZone(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, "spender not found")
+	}
+	if err != nil {
+		mlog.L(c).Error("query spender error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderCategorySummary error")
+	}
+
+	period, err := parsePeriod(c, loc)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	totals, err := h.getCategoryTotals(ctx, id, period)
+	if err != nil {
+		mlog.L(c).Error("query category totals error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderCategorySummary error")
+	}
+
+	resp, err := h.newConverter(ctx, home, time.Now()).categories(totals)
+	if errors.Is(err, fx.ErrNoRate) {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+	}
+	if err != nil {
+		mlog.L(c).Error("convert category totals error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderCategorySummary error")
+	}
+
+	if period.From != nil {
+		resp.From = period.From.Format(dateLayout)
+	}
+	if period.To != nil {
+		resp.To = period.To.AddDate(0, 0, -1).Format(dateLayout)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h handler) getCategoryTotals(ctx context.Context, id int, p Period) ([]categoryTotal, error) {
+	rows, err := h.db.QueryContext(ctx, categories_stmt, append([]any{id}, p.args()...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []categoryTotal
+	for rows.Next() {
+		var t categoryTotal
+		if err := rows.Scan(&t.Category, &t.TransactionType, &t.Currency, &t.TotalAmount, &t.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// categories merges the totals of a category recorded in several currencies
+// and works out the share of every category within its transaction type.
+func (cv *converter) categories(totals []categoryTotal) (CategorySummaryResponse, error) {
+	byType := map[string]map[string]*CategoryShare{
+		"income":  {},
+		"expense": {},
+	}
+	for _, t := range totals {
+		shares, ok := byType[t.TransactionType]
+		if !ok {
+			continue
+		}
+		ct, err := cv.convert(t.SummaryTransaction)
+		if err != nil {
+			return CategorySummaryResponse{}, err
+		}
+
+		share, ok := shares[t.Category]
+		if !ok {
+			share = &CategoryShare{Category: t.Category}
+			shares[t.Category] = share
+		}
+		share.Total += ct.TotalAmount
+		share.Count += t.Count
+	}
+
+	return CategorySummaryResponse{
+		Currency: cv.home,
+		Income:   breakdown(byType["income"]),
+		Expense:  breakdown(byType["expense"]),
+	}, nil
+}
+
+func breakdown(shares map[string]*CategoryShare) CategoryBreakdown {
+	b := CategoryBreakdown{Categories: []CategoryShare{}}
+	for _, s := range shares {
+		b.Total += s.Total
+		b.Count += s.Count
+	}
+	for _, s := range shares {
+		s.Percent = s.Total.Percent(b.Total)
+		b.Categories = append(b.Categories, *s)
+	}
+
+	// largest share first, ties in alphabetical order
+	sort.Slice(b.Categories, func(i, j int) bool {
+		if b.Categories[i].Total != b.Categories[j].Total {
+			return b.Categories[i].Total > b.Categories[j].Total
+		}
+		return b.Categories[i].Category < b.Categories[j].Category
+	})
+	return b
+}
@@ -0,0 +1,104 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSpenderCategorySummary(t *testing.T) {
+	newContext := func(query string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/transactions/summary/categories?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		return c, rec
+	}
+
+	t.Run("totals, counts and shares per category", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		bkk, _ := time.LoadLocation("Asia/Bangkok")
+		from := time.Date(2024, 5, 1, 0, 0, 0, 0, bkk)
+		to := time.Date(2024, 6, 1, 0, 0, 0, 0, bkk)
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+		mock.ExpectQuery(categories_stmt).WithArgs(1, from, to, false).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "tran_type", "currency", "total_amount", "total_count"}).
+				AddRow("Salary", "income", "THB", "30000.00", 1).
+				AddRow("Food", "expense", "THB", "3000.00", 20).
+				AddRow("Food", "expense", "JPY", "4000.00", 2).
+				AddRow("Travel", "expense", "THB", "6062.00", 3))
+		mock.ExpectQuery(`SELECT base, rate FROM fx_rate WHERE ((base = $1 AND quote = $2) OR (base = $2 AND quote = $1)) AND effective_date <= $3 ORDER BY effective_date DESC, base = $1 DESC LIMIT 1;`).
+			WithArgs("JPY", "THB", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("JPY", "0.2345"))
+
+		c, rec := newContext("from=2024-05-01&to=2024-05-31")
+		err := handler{db: db}.GetSpenderCategorySummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"currency": "THB",
+			"from": "2024-05-01",
+			"to": "2024-05-31",
+			"income": {"total": 30000, "count": 1, "categories": [
+				{"category": "Salary", "total": 30000, "count": 1, "percent": 100}
+			]},
+			"expense": {"total": 10000, "count": 25, "categories": [
+				{"category": "Travel", "total": 6062, "count": 3, "percent": 60.62},
+				{"category": "Food", "total": 3938, "count": 22, "percent": 39.38}
+			]}
+		}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no transactions gives empty breakdowns", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+		mock.ExpectQuery(categories_stmt).WithArgs(1, nil, nil, false).
+			WillReturnRows(sqlmock.NewRows([]string{"category", "tran_type", "currency", "total_amount", "total_count"}))
+
+		c, rec := newContext("")
+		err := handler{db: db}.GetSpenderCategorySummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"currency": "THB",
+			"income": {"total": 0, "count": 0, "categories": []},
+			"expense": {"total": 0, "count": 0, "categories": []}
+		}`, rec.Body.String())
+	})
+
+	t.Run("unknown spender", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}))
+
+		c, rec := newContext("")
+		err := handler{db: db}.GetSpenderCategorySummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+
+		c, rec := newContext("from=2024-05-31&to=2024-05-01")
+		err := handler{db: db}.GetSpenderCategorySummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
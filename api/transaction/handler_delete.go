@@ -0,0 +1,92 @@
+package transaction
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// transferCond matches the target transaction and the other side of its
+// transfer, so both sides are deleted and restored together.
+const transferCond = ` AND (id = target.target_id OR transfer_id = target.target_transfer)`
+
+const (
+	dStmt = `WITH target AS (SELECT id AS target_id, transfer_id AS target_transfer FROM transaction WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2)) ` +
+		`UPDATE transaction SET deleted_at = now(), version = version + 1 FROM target WHERE deleted_at IS NULL` + transferCond + `;`
+	rStmt = `WITH target AS (SELECT id AS target_id, transfer_id AS target_transfer FROM transaction WHERE id = $1 AND deleted_at IS NOT NULL), ` +
+		`restored AS (UPDATE transaction SET deleted_at = NULL, version = version + 1 FROM target WHERE deleted_at IS NOT NULL` + transferCond + ` RETURNING ` + selectColumns + `, version) ` +
+		`SELECT ` + selectColumns + `, version FROM restored WHERE id = $1;`
+)
+
+// Delete soft deletes a transaction, it is kept in the table until restored.
+// Deleting either side of a transfer deletes the other side too.
+func (h handler) Delete(c echo.Context) error {
+	if !h.flag.EnableDeleteTransaction {
+		return c.JSON(http.StatusForbidden, "delete transaction feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	ifMatch, err := etag.IfMatch(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	res, err := h.db.ExecContext(ctx, dStmt, id, ifMatch)
+	if err != nil {
+		logger.Error("exec error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		logger.Error("rows affected error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if affected == 0 {
+		return h.missed(c, id, ifMatch)
+	}
+
+	logger.Info("delete successfully", zap.Int("id", id))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Restore undoes a soft delete and returns the restored transaction, along
+// with the other side of a transfer.
+func (h handler) Restore(c echo.Context) error {
+	if !h.flag.EnableDeleteTransaction {
+		return c.JSON(http.StatusForbidden, "delete transaction feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	var tR TransactionResponse
+	var version int64
+	err = h.db.QueryRowContext(ctx, rStmt, id).Scan(append(tR.fields(), &version)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "deleted transaction not found"})
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("restore successfully", zap.Int64("id", tR.ID))
+	etag.Set(c, version)
+	return c.JSON(http.StatusOK, tR)
+}
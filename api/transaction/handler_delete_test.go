@@ -0,0 +1,176 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteTransaction(t *testing.T) {
+	t.Run("delete transaction successfully", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("api/v1/transactions/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectExec(dStmt).WithArgs(1, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: true}, db)
+		err := h.Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("delete transaction fail when transaction does not exist", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectExec(dStmt).WithArgs(99, nil).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: true}, db)
+		err := h.Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "transaction not found")
+	})
+
+	t.Run("delete transaction fail when version does not match", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set("If-Match", `"1"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectExec(dStmt).WithArgs(1, int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(versionStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: true}, db)
+		err := h.Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	})
+
+	t.Run("delete transaction fail when feature toggle is disable", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: false}, nil)
+		err := h.Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("delete transaction fail on database", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectExec(dStmt).WithArgs(1, nil).WillReturnError(assert.AnError)
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: true}, db)
+		err := h.Delete(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestRestoreTransaction(t *testing.T) {
+	t.Run("restore transaction successfully", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+		row := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}).
+			AddRow(1, date, 1000.00, "Food", "expense", "Lunch", "https://example.com/image1.jpg", 1, "THB", 3)
+		mock.ExpectQuery(rStmt).WithArgs(1).WillReturnRows(row)
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: true}, db)
+		err := h.Restore(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"id":1,"date":"2024-04-30T09:00:00Z","amount":1000,"category":"Food","transaction_type":"expense","note":"Lunch","image_url":"https://example.com/image1.jpg","spender_id":1,"currency":"THB"}`, rec.Body.String())
+	})
+
+	t.Run("restore transaction fail when transaction is not deleted", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectQuery(rStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		h := New(config.FeatureFlag{EnableDeleteTransaction: true}, db)
+		err := h.Restore(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
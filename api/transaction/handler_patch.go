@@ -0,0 +1,218 @@
+package transaction
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// patchColumn describes how a member of a merge patch document maps onto a
+// column. Members whose column is NOT NULL in spirit cannot be removed with a
+// JSON null, the others fall back to their column default.
+type patchColumn struct {
+	name     string
+	field    string
+	required bool
+	null     any
+	value    func(TransactionRequest) any
+}
+
+var patchColumns = []patchColumn{
+	{name: "date", field: "Date", required: true, value: func(r TransactionRequest) any { return r.Date }},
+	{name: "amount", field: "Amount", required: true, value: func(r TransactionRequest) any { return r.Amount }},
+	{name: "category", field: "Category", null: "", value: func(r TransactionRequest) any { return r.Category }},
+	{name: "transaction_type", field: "TransactionType", required: true, value: func(r TransactionRequest) any { return r.TransactionType }},
+	{name: "note", field: "Note", null: "", value: func(r TransactionRequest) any { return r.Note }},
+	{name: "image_url", field: "ImageUrl", null: "", value: func(r TransactionRequest) any { return r.ImageUrl }},
+	{name: "spender_id", field: "SpenderID", null: nil, value: func(r TransactionRequest) any { return r.SpenderID }},
+	{name: "currency", field: "Currency", required: true, value: func(r TransactionRequest) any { return r.Currency }},
+}
+
+// Patch applies a JSON Merge Patch (RFC 7386) to a transaction, only the
+// members present in the document are written.
+func (h handler) Patch(c echo.Context) error {
+	if !h.flag.EnableUpdateTransaction {
+		return c.JSON(http.StatusForbidden, "update transaction feature is disabled")
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid ID"})
+	}
+
+	ifMatch, err := etag.IfMatch(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid transaction request"})
+	}
+	p, err := buildPatch(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if len(p.fields) > 0 {
+		err := validate.Partial(c, &p.req, p.fields...)
+		// a partial validation does not dive into the split lines
+		if err == nil && p.splits {
+			err = validateSplits(c, p.req.Splits)
+		}
+		if err != nil {
+			return validate.Fail(c, err)
+		}
+	}
+
+	args := append(p.args, id, ifMatch)
+	query := fmt.Sprintf("UPDATE transaction SET %s WHERE id = $%d AND deleted_at IS NULL AND ($%d::int IS NULL OR version = $%d) RETURNING %s, version;",
+		strings.Join(append(p.sets, "version = version + 1"), ", "), len(args)-1, len(args), len(args), selectColumns)
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("begin transaction error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	var tR TransactionResponse
+	var version int64
+	err = tx.QueryRowContext(ctx, query, args...).Scan(append(tR.fields(), &version)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h.missed(c, id, ifMatch)
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	if p.splits {
+		err = replaceSplits(ctx, tx, tR.ID, p.req.Splits)
+		tR.Splits = p.req.Splits
+	} else {
+		tR.Splits, err = getSplits(ctx, tx, tR.ID)
+	}
+	if err != nil {
+		logger.Error("splits error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	// the amount and the splits may be patched apart, they must still agree
+	if err := checkSplits(tR.Amount, tR.Splits); err != nil {
+		return validate.Fail(c, err)
+	}
+	if p.tags {
+		err = replaceTags(ctx, tx, tR.ID, p.req.Tags)
+		tR.Tags = p.req.Tags
+	} else {
+		tR.Tags, err = getTags(ctx, tx, tR.ID)
+	}
+	if err != nil {
+		logger.Error("tags error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if err := mirror(ctx, tx, tR); err != nil {
+		return failMirror(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("commit error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("patch successfully", zap.Int64("id", tR.ID))
+	h.spent(ctx, tR)
+	etag.Set(c, version)
+	return c.JSON(http.StatusOK, tR)
+}
+
+type patch struct {
+	sets []string
+	args []any
+	// req holds the decoded members and fields names those to validate,
+	// members removed with a JSON null have nothing left to check
+	req    TransactionRequest
+	fields []string
+	// splits and tags are set when the document replaces the split lines
+	// or the tags
+	splits bool
+	tags   bool
+}
+
+// buildPatch turns a merge patch document into SET assignments and their
+// arguments. The document is decoded into a TransactionRequest first so the
+// members are type checked exactly like the body of Create.
+func buildPatch(body []byte) (patch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return patch{}, errors.New("Invalid transaction request")
+	}
+
+	var p patch
+	if err := json.Unmarshal(body, &p.req); err != nil {
+		return patch{}, errors.New("Invalid transaction request")
+	}
+	p.req.Currency = strings.ToUpper(p.req.Currency)
+	p.req.Tags = normalizeTags(p.req.Tags)
+	if _, ok := members["currency"]; ok && p.req.Currency == "" {
+		return patch{}, errors.New("currency cannot be removed")
+	}
+
+	known := map[string]bool{}
+	for _, col := range patchColumns {
+		known[col.name] = true
+		raw, ok := members[col.name]
+		if !ok {
+			continue
+		}
+
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if col.required {
+				return patch{}, fmt.Errorf("%s cannot be removed", col.name)
+			}
+			p.args = append(p.args, col.null)
+		} else {
+			p.args = append(p.args, col.value(p.req))
+			p.fields = append(p.fields, col.field)
+		}
+		p.sets = append(p.sets, fmt.Sprintf("%s = $%d", col.name, len(p.args)))
+	}
+
+	if raw, ok := members["splits"]; ok {
+		known["splits"] = true
+		p.splits = true
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			p.fields = append(p.fields, "Splits")
+		}
+	}
+	if raw, ok := members["tags"]; ok {
+		known["tags"] = true
+		p.tags = true
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			p.fields = append(p.fields, "Tags")
+		}
+	}
+
+	for name := range members {
+		if !known[name] {
+			return patch{}, fmt.Errorf("unknown field: %s", name)
+		}
+	}
+	if len(p.sets) == 0 && !p.splits && !p.tags {
+		return patch{}, errors.New("no fields to update")
+	}
+
+	return p, nil
+}
@@ -0,0 +1,251 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchTransaction(t *testing.T) {
+	t.Run("patch only the supplied fields", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"note": "Dinner", "image_url": null}`))
+		req.Header.Set("If-Match", `"2"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("api/v1/transactions/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+		row := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}).
+			AddRow(1, date, 1000.00, "Food", "expense", "Dinner", "", 1, "THB", 3)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE transaction SET note = $1, image_url = $2, version = version + 1 WHERE id = $3 AND deleted_at IS NULL AND ($4::int IS NULL OR version = $4) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`).
+			WithArgs("Dinner", "", 1, int64(2)).WillReturnRows(row)
+		mock.ExpectQuery(splitsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"category", "amount", "spender_id"}))
+		mock.ExpectQuery(tagsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery(mirrorStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"transaction_type", "spender_id"}))
+		mock.ExpectCommit()
+
+		e.Validator = validate.New(db)
+		h := New(config.FeatureFlag{EnableUpdateTransaction: true}, db)
+		err := h.Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"id":1,"date":"2024-04-30T09:00:00Z","amount":1000,"category":"Food","transaction_type":"expense","note":"Dinner","image_url":"","spender_id":1,"currency":"THB"}`, rec.Body.String())
+	})
+
+	t.Run("patch transaction fail when transaction does not exist", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"amount": 50}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE transaction SET amount = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND ($3::int IS NULL OR version = $3) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`).
+			WithArgs(money.MustParse("50"), 99, nil).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		e.Validator = validate.New(db)
+		h := New(config.FeatureFlag{EnableUpdateTransaction: true}, db)
+		err := h.Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("patch amount that no longer matches the splits", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"amount": 50}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE transaction SET amount = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND ($3::int IS NULL OR version = $3) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`).
+			WithArgs(money.MustParse("50"), 1, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}).
+				AddRow(1, date, "50.00", "Supermarket", "expense", "", "", 1, "THB", 2))
+		mock.ExpectQuery(splitsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"category", "amount", "spender_id"}).
+			AddRow("Groceries", "70.00", nil).
+			AddRow("Household", "30.00", nil))
+		mock.ExpectRollback()
+
+		e.Validator = validate.New(db)
+		err := New(config.FeatureFlag{EnableUpdateTransaction: true}, db).Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), "must add up to the amount 50.00, not 100.00")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("patch only the splits", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"splits": [{"category": "Groceries", "amount": 60}, {"category": "Household", "amount": 40, "spender_id": 2}]}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE transaction SET version = version + 1 WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`).
+			WithArgs(1, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}).
+				AddRow(1, date, "100.00", "Supermarket", "expense", "", "", 1, "THB", 2))
+		mock.ExpectExec(deleteSplitsStmt).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(insertSplitStmt).WithArgs(int64(1), "Groceries", money.MustParse("60"), nil).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(insertSplitStmt).WithArgs(int64(1), "Household", money.MustParse("40"), int64(2)).WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectQuery(tagsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery(mirrorStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"transaction_type", "spender_id"}))
+		mock.ExpectCommit()
+
+		e.Validator = validate.New(nil)
+		err := New(config.FeatureFlag{EnableUpdateTransaction: true}, db).Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"splits":[{"category":"Groceries","amount":60},{"category":"Household","amount":40,"spender_id":2}]`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("patch transaction fail when supplied field is invalid", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"amount": 0}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := New(config.FeatureFlag{EnableUpdateTransaction: true}, nil)
+		err := h.Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message":"validation failed","errors":[{"field":"amount","message":"must be greater than 0"}]}`, rec.Body.String())
+	})
+
+	t.Run("patch transaction fail when a split line is invalid", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+		e.Validator = validate.New(nil)
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"splits": [{"category": "Groceries", "amount": 60}, {"category": "", "amount": -40}]}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := New(config.FeatureFlag{EnableUpdateTransaction: true}, nil)
+		err := h.Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message":"validation failed","errors":[
+			{"field":"splits[1].category","message":"is required"},
+			{"field":"splits[1].amount","message":"must be greater than 0"}
+		]}`, rec.Body.String())
+	})
+
+	t.Run("patch transaction fail when feature toggle is disable", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"note": "Dinner"}`))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := New(config.FeatureFlag{EnableUpdateTransaction: false}, nil)
+		err := h.Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestBuildPatch(t *testing.T) {
+	t.Run("spender id can be removed", func(t *testing.T) {
+		p, err := buildPatch([]byte(`{"spender_id": null, "category": "Travel"}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"category = $1", "spender_id = $2"}, p.sets)
+		assert.Equal(t, []any{"Travel", nil}, p.args)
+		assert.Equal(t, []string{"Category"}, p.fields)
+	})
+
+	t.Run("required field cannot be removed", func(t *testing.T) {
+		_, err := buildPatch([]byte(`{"amount": null}`))
+
+		assert.EqualError(t, err, "amount cannot be removed")
+	})
+
+	t.Run("currency is normalised and cannot be emptied", func(t *testing.T) {
+		p, err := buildPatch([]byte(`{"currency": "jpy"}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"JPY"}, p.args)
+
+		_, err = buildPatch([]byte(`{"currency": ""}`))
+
+		assert.EqualError(t, err, "currency cannot be removed")
+	})
+
+	t.Run("wrong type is rejected like create", func(t *testing.T) {
+		_, err := buildPatch([]byte(`{"amount": "a lot"}`))
+
+		assert.EqualError(t, err, "Invalid transaction request")
+	})
+
+	t.Run("unknown field is rejected", func(t *testing.T) {
+		_, err := buildPatch([]byte(`{"id": 2}`))
+
+		assert.EqualError(t, err, "unknown field: id")
+	})
+
+	t.Run("empty patch is rejected", func(t *testing.T) {
+		_, err := buildPatch([]byte(`{}`))
+
+		assert.EqualError(t, err, "no fields to update")
+	})
+}
@@ -2,115 +2,293 @@ package transaction
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
 	"github.com/labstack/echo/v4"
-) 
-
+	"go.uber.org/zap"
+)
 
-type SummaryTransaction struct{
+type SummaryTransaction struct {
 	TransactionType string
-	TotalAmount	float64
+	TotalAmount     money.Amount
+	Currency        string
 }
 
-
-type SummaryResponse struct {
-	Summary struct {
-		TotalIncome    float64 `json:"total_income"`
-		TotalExpenses  float64 `json:"total_expenses"`
-		CurrentBalance float64 `json:"current_balance"`
-	} `json:"summary"`
+type Summary struct {
+	TotalIncome    money.Amount `json:"total_income"`
+	TotalExpenses  money.Amount `json:"total_expenses"`
+	CurrentBalance money.Amount `json:"current_balance"`
 }
 
+// CurrencySummary is the part of a summary recorded in one currency, in
+// that currency.
+type CurrencySummary struct {
+	Currency string `json:"currency"`
+	Summary
+}
 
+// SummaryResponse reports the totals in the home currency of the spender,
+// each currency is converted at the latest known rate.
+type SummaryResponse struct {
+	Summary    Summary           `json:"summary"`
+	Currency   string            `json:"currency,omitempty"`
+	ByCurrency []CurrencySummary `json:"by_currency,omitempty"`
+	From       string            `json:"from,omitempty"`
+	To         string            `json:"to,omitempty"`
+	GroupBy    string            `json:"group_by,omitempty"`
+	Timezone   string            `json:"timezone,omitempty"`
+	Series     []Bucket          `json:"series,omitempty"`
+	Statistics *Statistics       `json:"statistics,omitempty"`
+	// UnknownTypes lists transactions of a type other than income or
+	// expense, which no other figure includes.
+	UnknownTypes []UnknownType `json:"unknown_transaction_types,omitempty"`
+}
 
 const (
-	summary_stmt  = `SELECT sum(amount) as total_amount, transaction_type as tran_type FROM transaction WHERE spender_id = $1 group by spender_id ,transaction_type`
+	periodCond   = ` AND ($2::timestamptz IS NULL OR date >= $2) AND ($3::timestamptz IS NULL OR date < $3) AND ($4::bool IS NOT TRUE OR transfer_id IS NULL)`
+	summary_stmt = `SELECT sum(amount) as total_amount, transaction_type as tran_type, currency FROM transaction_line WHERE spender_id = $1 AND deleted_at IS NULL` + periodCond + ` group by spender_id ,transaction_type, currency`
+	series_stmt  = `SELECT date_trunc($5, date AT TIME ZONE $6) as bucket, sum(amount) as total_amount, transaction_type as tran_type, currency FROM transaction_line WHERE spender_id = $1 AND deleted_at IS NULL AND date IS NOT NULL` + periodCond + ` group by bucket, transaction_type, currency order by bucket`
+	spenderStmt  = `SELECT currency, timezone FROM spender WHERE id = $1;`
 )
 
-func (h handler) GetSpenderSummary(c echo.Context) error{
-	id , err := strconv.Atoi(c.Param("id"))
+func (h handler) GetSpenderSummary(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-        return c.JSON(http.StatusBadRequest, "invalid spender id")
-    }
+		return c.JSON(http.StatusBadRequest, "invalid spender id")
+	}
 
 	ctx := c.Request().Context()
-	summaryTran, err := h.getSummaryTransaction(ctx,id)
 
+	home, tz, loc, err := h.getSpenderZone(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, "spender not found")
+	}
+	if err != nil {
+		mlog.L(c).Error("query spender error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderSummary error")
+	}
+
+	period, err := parsePeriod(c, loc)
 	if err != nil {
-        fmt.Println(err.Error())
-        return c.JSON(http.StatusInternalServerError, "getSpenderSummary error")
-    }
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	summaryTran, err := h.getSummaryTransaction(ctx, id, period)
 
-    if len(summaryTran) == 0{
-        return c.JSON(http.StatusNotFound, " transaction not found")
-    }
+	if err != nil {
+		mlog.L(c).Error("query summary error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderSummary error")
+	}
+
+	if len(summaryTran) == 0 && period.allTime() {
+		return c.JSON(http.StatusNotFound, " transaction not found")
+	}
 
-	return c.JSON(http.StatusOK, calculateSummary(summaryTran))
+	cv := h.newConverter(ctx, home, time.Now())
+	resp, err := cv.summary(summaryTran)
+	if err == nil {
+		var stats Statistics
+		stats, resp.UnknownTypes, err = h.getStatistics(ctx, cv, id, period)
+		resp.Statistics = &stats
+	}
+	if err == nil && period.GroupBy != "" {
+		resp.Series, err = h.getSeries(ctx, cv, id, period, tz)
+		resp.GroupBy, resp.Timezone = period.GroupBy, tz
+	}
+	if errors.Is(err, fx.ErrNoRate) {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+	}
+	if errors.As(err, &queryError{}) {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+	if err != nil {
+		mlog.L(c).Error("summarize error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderSummary error")
+	}
+
+	if period.From != nil {
+		resp.From = period.From.Format(dateLayout)
+	}
+	if period.To != nil {
+		resp.To = period.To.AddDate(0, 0, -1).Format(dateLayout)
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
-func (h handler) getSummaryTransaction(ctx context.Context, id int)(summaryTrans []SummaryTransaction , err error){
-	rows, err := h.db.QueryContext(ctx, summary_stmt,id)
-	if err != nil {	
-		return nil,err
+// getSpenderZone returns the home currency and timezone of a spender.
+func (h handler) getSpenderZone(ctx context.Context, id int) (home, tz string, loc *time.Location, err error) {
+	if err := h.db.QueryRowContext(ctx, spenderStmt, id).Scan(&home, &tz); err != nil {
+		return "", "", nil, err
+	}
+	loc, err = time.LoadLocation(tz)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return home, tz, loc, nil
+}
+
+// converter converts summary rows into the home currency of the spender.
+type converter struct {
+	ctx  context.Context
+	home string
+	fx   *fx.Converter
+}
+
+func (h handler) newConverter(ctx context.Context, home string, on time.Time) *converter {
+	return &converter{ctx: ctx, home: home, fx: fx.NewConverter(h.db, home, on)}
+}
+
+func (cv *converter) convert(t SummaryTransaction) (SummaryTransaction, error) {
+	amount, err := cv.fx.Convert(cv.ctx, t.TotalAmount, t.Currency)
+	if err != nil {
+		return SummaryTransaction{}, err
+	}
+	return SummaryTransaction{TransactionType: t.TransactionType, TotalAmount: amount, Currency: cv.home}, nil
+}
+
+// summary totals the transactions in the home currency and keeps a
+// subtotal for every currency they were recorded in.
+func (cv *converter) summary(transactions []SummaryTransaction) (SummaryResponse, error) {
+	converted := make([]SummaryTransaction, len(transactions))
+	byCurrency := map[string][]SummaryTransaction{}
+
+	for i, t := range transactions {
+		ct, err := cv.convert(t)
+		if err != nil {
+			return SummaryResponse{}, err
+		}
+		converted[i] = ct
+		byCurrency[t.Currency] = append(byCurrency[t.Currency], t)
+	}
+
+	resp := calculateSummary(converted)
+	resp.Currency = cv.home
+	currencies := make([]string, 0, len(byCurrency))
+	for cur := range byCurrency {
+		currencies = append(currencies, cur)
+	}
+	sort.Strings(currencies)
+	for _, cur := range currencies {
+		resp.ByCurrency = append(resp.ByCurrency, CurrencySummary{
+			Currency: cur,
+			Summary:  calculateSummary(byCurrency[cur]).Summary,
+		})
+	}
+	return resp, nil
+}
+
+// getSeries totals every bucket of the period in the home currency.
+func (h handler) getSeries(ctx context.Context, cv *converter, id int, p Period, tz string) ([]Bucket, error) {
+	rows, err := h.db.QueryContext(ctx, series_stmt, append([]any{id}, append(p.args(), p.GroupBy, tz)...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byBucket := map[string][]SummaryTransaction{}
+	var first, last time.Time
+	for rows.Next() {
+		var bucket time.Time
+		var t SummaryTransaction
+		if err := rows.Scan(&bucket, &t.TotalAmount, &t.TransactionType, &t.Currency); err != nil {
+			return nil, err
+		}
+		// the bucket is a wall clock time in the spender's timezone
+		y, m, d := bucket.Date()
+		start := time.Date(y, m, d, 0, 0, 0, 0, p.loc)
+		if first.IsZero() {
+			first = start
+		}
+		last = start
+
+		ct, err := cv.convert(t)
+		if err != nil {
+			return nil, err
+		}
+		key := start.Format(dateLayout)
+		byBucket[key] = append(byBucket[key], ct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if first.IsZero() && (p.From == nil || p.To == nil) {
+		return nil, nil
+	}
+
+	starts, err := p.buckets(first, last)
+	if err != nil {
+		return nil, err
+	}
+	var series []Bucket
+	for _, start := range starts {
+		key := start.Format(dateLayout)
+		series = append(series, Bucket{Start: key, Summary: calculateSummary(byBucket[key]).Summary})
+	}
+	return series, nil
+}
+
+func (h handler) getSummaryTransaction(ctx context.Context, id int, p Period) (summaryTrans []SummaryTransaction, err error) {
+	rows, err := h.db.QueryContext(ctx, summary_stmt, append([]any{id}, p.args()...)...)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
 	var s []SummaryTransaction
 
-    for rows.Next() {
-        var totalAmount float64
-        var transactionType string
+	for rows.Next() {
+		var totalAmount money.Amount
+		var transactionType, currency string
 
-        if err := rows.Scan(&totalAmount, &transactionType); err != nil {
-            return nil, err
-        }
+		if err := rows.Scan(&totalAmount, &transactionType, &currency); err != nil {
+			return nil, err
+		}
 
-        s = append(s, SummaryTransaction{
-            TransactionType: transactionType,
-            TotalAmount:     totalAmount,
-        })
-    }
+		s = append(s, SummaryTransaction{
+			TransactionType: transactionType,
+			TotalAmount:     totalAmount,
+			Currency:        currency,
+		})
+	}
 
-    if err := rows.Err(); err != nil {
-        return nil, err
-    }
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-    return s, nil
+	return s, nil
 
 }
 
 func calculateSummary(transactions []SummaryTransaction) SummaryResponse {
-    // Initialize variables for total income and total expenses
-    totalIncome := 0.0
-    totalExpenses := 0.0
-
-    // Loop through transactions
-    for _, transaction := range transactions {
-        if transaction.TransactionType == "income" {
-            totalIncome += transaction.TotalAmount
-        } else if transaction.TransactionType == "expense" {
-            totalExpenses += transaction.TotalAmount
-        }
-    }
-
-    // Calculate current balance
-    currentBalance := totalIncome - totalExpenses
-
-    // Create and return summary response
-    return SummaryResponse{
-        Summary: struct {
-            TotalIncome    float64 `json:"total_income"`
-            TotalExpenses  float64 `json:"total_expenses"`
-            CurrentBalance float64 `json:"current_balance"`
-        }{
-            TotalIncome:    totalIncome,
-            TotalExpenses:  totalExpenses,
-            CurrentBalance: currentBalance,
-        },
-    }
-}
+	// Initialize variables for total income and total expenses
+	var totalIncome, totalExpenses money.Amount
 
+	// Loop through transactions
+	for _, transaction := range transactions {
+		if transaction.TransactionType == "income" {
+			totalIncome += transaction.TotalAmount
+		} else if transaction.TransactionType == "expense" {
+			totalExpenses += transaction.TotalAmount
+		}
+	}
+
+	// Calculate current balance
+	currentBalance := totalIncome - totalExpenses
 
+	// Create and return summary response
+	return SummaryResponse{
+		Summary: Summary{
+			TotalIncome:    totalIncome,
+			TotalExpenses:  totalExpenses,
+			CurrentBalance: currentBalance,
+		},
+	}
+}
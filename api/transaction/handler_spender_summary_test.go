@@ -4,13 +4,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
 
+var (
+	statColumns     = []string{"tran_type", "currency", "count", "total", "min", "max", "first", "last"}
+	categoryColumns = []string{"category", "tran_type", "currency", "total_amount", "total_count"}
+)
+
 func TestGetSummaryTransaction(t *testing.T) {
 	t.Run("get summary transaction successfully", func(t *testing.T) {
 		e := echo.New()
@@ -31,21 +38,21 @@ func TestGetSummaryTransaction(t *testing.T) {
 		
 		spender_id :=123
 		mock.ExpectQuery(summary_stmt).
-        WithArgs(spender_id).
-        WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type"}).
-            AddRow(500.00, "income").
-            AddRow(300.00, "expense"))
+        WithArgs(spender_id, nil, nil, false).
+        WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type", "currency"}).
+            AddRow(500.00, "income", "THB").
+            AddRow(300.00, "expense", "THB"))
 
 		cfg := config.FeatureFlag{EnableCreateSpender: true}
 
 		h := New(cfg, db)
 
-		summaryTrans , err := h.getSummaryTransaction(c.Request().Context(),spender_id)
+		summaryTrans , err := h.getSummaryTransaction(c.Request().Context(),spender_id, Period{})
 
 		assert.NoError(t, err)
 		expectedSummary := []SummaryTransaction{
-			{TransactionType: "income", TotalAmount: 500.00},
-			{TransactionType: "expense", TotalAmount: 300.00},
+			{TransactionType: "income", TotalAmount: money.MustParse("500"), Currency: "THB"},
+			{TransactionType: "expense", TotalAmount: money.MustParse("300"), Currency: "THB"},
 		}
 		assert.Equal(t, expectedSummary, summaryTrans)
 	})
@@ -72,11 +79,31 @@ func TestGetSpenderSummary(t *testing.T) {
 	defer mock.ExpectationsWereMet()
 
 	spender_id :=123
+	mock.ExpectQuery(spenderStmt).WithArgs(spender_id).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
 	mock.ExpectQuery(summary_stmt).
-	WithArgs(spender_id).
-	WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type"}).
-		AddRow(500.00, "income").
-		AddRow(300.00, "expense"))
+	WithArgs(spender_id, nil, nil, false).
+	WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type", "currency"}).
+		AddRow(500.00, "income", "THB").
+		AddRow(300.00, "expense", "THB").
+		AddRow(50.00, "refund", "THB"))
+	mock.ExpectQuery(stats_stmt).
+	WithArgs(spender_id, nil, nil, false).
+	WillReturnRows(sqlmock.NewRows(statColumns).
+		AddRow("income", "THB", 1, "500.00", "500.00", "500.00", time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)).
+		AddRow("expense", "THB", 2, "300.00", "100.00", "200.00", time.Date(2024, 5, 1, 5, 0, 0, 0, time.UTC), time.Date(2024, 5, 3, 18, 0, 0, 0, time.UTC)).
+		AddRow("refund", "THB", 1, "50.00", "50.00", "50.00", time.Date(2024, 5, 2, 5, 0, 0, 0, time.UTC), time.Date(2024, 5, 2, 5, 0, 0, 0, time.UTC)))
+	mock.ExpectQuery(categories_stmt).
+	WithArgs(spender_id, nil, nil, false).
+	WillReturnRows(sqlmock.NewRows(categoryColumns).
+		AddRow("Salary", "income", "THB", "500.00", 1).
+		AddRow("Food", "expense", "THB", "100.00", 1).
+		AddRow("Travel", "expense", "THB", "200.00", 1).
+		AddRow("Food", "refund", "THB", "50.00", 1))
+	mock.ExpectQuery(median_stmt).
+	WithArgs(spender_id, nil, nil, false, `{"THB"}`, `{"1.000000000000"}`).
+	WillReturnRows(sqlmock.NewRows([]string{"tran_type", "median"}).
+		AddRow("income", 100000.0).
+		AddRow("expense", 30000.0))
 
 	// Step 4: Create a handler instance with the mocked database
 	h := handler{
@@ -103,28 +130,204 @@ func TestGetSpenderSummary(t *testing.T) {
 			"total_income": 500,
 			"total_expenses": 300,
 			"current_balance": 200
-		}
+		},
+		"currency": "THB",
+		"by_currency": [
+			{"currency": "THB", "total_income": 500, "total_expenses": 300, "current_balance": 200}
+		],
+		"statistics": {
+			"days": 4,
+			"income": {"total": 500, "count": 1, "average_per_day": 125, "median": 500, "min": 500, "max": 500,
+				"largest_category": {"category": "Salary", "total": 500}},
+			"expense": {"total": 300, "count": 2, "average_per_day": 75, "median": 150, "min": 100, "max": 200,
+				"largest_category": {"category": "Travel", "total": 200}}
+		},
+		"unknown_transaction_types": [
+			{"transaction_type": "refund", "count": 1, "total": 50}
+		]
 	}`
 	assert.JSONEq(t, expectedResponseBody, rec.Body.String())
 }
 
+func TestGetSpenderSummaryMultiCurrency(t *testing.T) {
+	lookupStmt := `SELECT base, rate FROM fx_rate WHERE ((base = $1 AND quote = $2) OR (base = $2 AND quote = $1)) AND effective_date <= $3 ORDER BY effective_date DESC, base = $1 DESC LIMIT 1;`
+
+	setup := func(t *testing.T) (echo.Context, *httptest.ResponseRecorder, sqlmock.Sqlmock, handler) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatalf("failed to create sqlmock: %s", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/transactions/summary", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+		mock.ExpectQuery(summary_stmt).WithArgs(1, nil, nil, false).
+			WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type", "currency"}).
+				AddRow("500.00", "income", "THB").
+				AddRow("1000.00", "expense", "JPY").
+				AddRow("10.00", "income", "USD"))
+
+		return c, rec, mock, handler{db: db}
+	}
+
+	t.Run("totals are converted into the home currency", func(t *testing.T) {
+		c, rec, mock, h := setup(t)
+		mock.ExpectQuery(lookupStmt).WithArgs("JPY", "THB", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("JPY", "0.23450000"))
+		mock.ExpectQuery(lookupStmt).WithArgs("USD", "THB", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}).AddRow("THB", "0.02500000"))
+		mock.ExpectQuery(stats_stmt).WithArgs(1, nil, nil, false).
+			WillReturnRows(sqlmock.NewRows(statColumns).
+				AddRow("income", "THB", 1, "500.00", "500.00", "500.00", time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)).
+				AddRow("expense", "JPY", 1, "1000.00", "1000.00", "1000.00", time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC)).
+				AddRow("income", "USD", 1, "10.00", "10.00", "10.00", time.Date(2024, 5, 1, 5, 0, 0, 0, time.UTC), time.Date(2024, 5, 1, 5, 0, 0, 0, time.UTC)))
+		mock.ExpectQuery(categories_stmt).WithArgs(1, nil, nil, false).
+			WillReturnRows(sqlmock.NewRows(categoryColumns).
+				AddRow("Salary", "income", "THB", "500.00", 1).
+				AddRow("Food", "expense", "JPY", "1000.00", 1).
+				AddRow("Gift", "income", "USD", "10.00", 1))
+		// every amount is ranked in baht, 500 and 400 of income have a median of 450
+		mock.ExpectQuery(median_stmt).WithArgs(1, nil, nil, false, `{"THB","JPY","USD"}`, `{"1.000000000000","0.234500000000","40.000000000000"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"tran_type", "median"}).
+				AddRow("income", 90000.0).
+				AddRow("expense", 46900.0))
+
+		err := h.GetSpenderSummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{
+			"summary": {"total_income": 900, "total_expenses": 234.5, "current_balance": 665.5},
+			"currency": "THB",
+			"by_currency": [
+				{"currency": "JPY", "total_income": 0, "total_expenses": 1000, "current_balance": -1000},
+				{"currency": "THB", "total_income": 500, "total_expenses": 0, "current_balance": 500},
+				{"currency": "USD", "total_income": 10, "total_expenses": 0, "current_balance": 10}
+			],
+			"statistics": {
+				"days": 1,
+				"income": {"total": 900, "count": 2, "average_per_day": 900, "median": 450, "min": 400, "max": 500,
+					"largest_category": {"category": "Salary", "total": 500}},
+				"expense": {"total": 234.5, "count": 1, "average_per_day": 234.5, "median": 234.5, "min": 234.5, "max": 234.5,
+					"largest_category": {"category": "Food", "total": 234.5}}
+			}
+		}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("bad period is bad request", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/transactions/summary?group_by=hour", nil)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		err := handler{db: db}.GetSpenderSummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("missing rate is unprocessable entity", func(t *testing.T) {
+		c, rec, mock, h := setup(t)
+		mock.ExpectQuery(lookupStmt).WithArgs("JPY", "THB", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"base", "rate"}))
+
+		err := h.GetSpenderSummary(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message": "no fx rate from JPY to THB"}`, rec.Body.String())
+	})
+}
+
+func TestGetSpenderSummarySeries(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %s", err)
+	}
+	defer db.Close()
+
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, bkk)
+	to := time.Date(2024, 8, 1, 0, 0, 0, 0, bkk)
+	mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+	mock.ExpectQuery(summary_stmt).WithArgs(1, from, to, false).
+		WillReturnRows(sqlmock.NewRows([]string{"total_amount", "tran_type", "currency"}).
+			AddRow("3000.00", "income", "THB").
+			AddRow("500.00", "expense", "THB"))
+	mock.ExpectQuery(stats_stmt).WithArgs(1, from, to, false).
+		WillReturnRows(sqlmock.NewRows(statColumns).
+			AddRow("income", "THB", 1, "3000.00", "3000.00", "3000.00", time.Date(2024, 5, 10, 3, 0, 0, 0, time.UTC), time.Date(2024, 5, 10, 3, 0, 0, 0, time.UTC)).
+			AddRow("expense", "THB", 1, "500.00", "500.00", "500.00", time.Date(2024, 7, 2, 3, 0, 0, 0, time.UTC), time.Date(2024, 7, 2, 3, 0, 0, 0, time.UTC)))
+	mock.ExpectQuery(categories_stmt).WithArgs(1, from, to, false).
+		WillReturnRows(sqlmock.NewRows(categoryColumns).
+			AddRow("Salary", "income", "THB", "3000.00", 1).
+			AddRow("Food", "expense", "THB", "500.00", 1))
+	mock.ExpectQuery(median_stmt).WithArgs(1, from, to, false, `{"THB"}`, `{"1.000000000000"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"tran_type", "median"}).
+			AddRow("income", 600000.0).
+			AddRow("expense", 100000.0))
+	mock.ExpectQuery(series_stmt).WithArgs(1, from, to, false, "month", "Asia/Bangkok").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "total_amount", "tran_type", "currency"}).
+			AddRow(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), "3000.00", "income", "THB").
+			AddRow(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), "500.00", "expense", "THB"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/transactions/summary?from=2024-05-01&to=2024-07-31&group_by=month", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	err = handler{db: db}.GetSpenderSummary(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"summary": {"total_income": 3000, "total_expenses": 500, "current_balance": 2500},
+		"currency": "THB",
+		"by_currency": [{"currency": "THB", "total_income": 3000, "total_expenses": 500, "current_balance": 2500}],
+		"from": "2024-05-01",
+		"to": "2024-07-31",
+		"group_by": "month",
+		"timezone": "Asia/Bangkok",
+		"series": [
+			{"start": "2024-05-01", "total_income": 3000, "total_expenses": 0, "current_balance": 3000},
+			{"start": "2024-06-01", "total_income": 0, "total_expenses": 0, "current_balance": 0},
+			{"start": "2024-07-01", "total_income": 0, "total_expenses": 500, "current_balance": -500}
+		],
+		"statistics": {
+			"days": 92,
+			"income": {"total": 3000, "count": 1, "average_per_day": 32.61, "median": 3000, "min": 3000, "max": 3000,
+				"largest_category": {"category": "Salary", "total": 3000}},
+			"expense": {"total": 500, "count": 1, "average_per_day": 5.43, "median": 500, "min": 500, "max": 500,
+				"largest_category": {"category": "Food", "total": 500}}
+		}
+	}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
 func TestCalculateSummary(t *testing.T) {
 	t.Run("Only income transactions", func(t *testing.T) {
 		// Test case 1: Only income transactions
 		transactions := []SummaryTransaction{
-			{TransactionType: "income", TotalAmount: 500.0},
-			{TransactionType: "income", TotalAmount: 300.0},
+			{TransactionType: "income", TotalAmount: money.MustParse("500")},
+			{TransactionType: "income", TotalAmount: money.MustParse("300")},
 		}
 		expectedSummary := SummaryResponse{
-			Summary: struct {
-				TotalIncome    float64 `json:"total_income"`
-				TotalExpenses  float64 `json:"total_expenses"`
-				CurrentBalance float64 `json:"current_balance"`
-			}{
-				TotalIncome:    800.0,
-				TotalExpenses:  0.0,
-				CurrentBalance: 800.0,
+			Summary: Summary{
+				TotalIncome:    money.MustParse("800"),
+				TotalExpenses:  money.MustParse("0"),
+				CurrentBalance: money.MustParse("800"),
 			},
 		}
 		assert.Equal(t, expectedSummary, calculateSummary(transactions))
@@ -133,18 +336,14 @@ func TestCalculateSummary(t *testing.T) {
 	t.Run("Only expense transactions", func(t *testing.T) {
 		// Test case 2: Only expense transactions
 		transactions := []SummaryTransaction{
-			{TransactionType: "expense", TotalAmount: 200.0},
-			{TransactionType: "expense", TotalAmount: 100.0},
+			{TransactionType: "expense", TotalAmount: money.MustParse("200")},
+			{TransactionType: "expense", TotalAmount: money.MustParse("100")},
 		}
 		expectedSummary := SummaryResponse{
-			Summary: struct {
-				TotalIncome    float64 `json:"total_income"`
-				TotalExpenses  float64 `json:"total_expenses"`
-				CurrentBalance float64 `json:"current_balance"`
-			}{
-				TotalIncome:    0.0,
-				TotalExpenses:  300.0,
-				CurrentBalance: -300.0,
+			Summary: Summary{
+				TotalIncome:    money.MustParse("0"),
+				TotalExpenses:  money.MustParse("300"),
+				CurrentBalance: money.MustParse("-300"),
 			},
 		}
 		assert.Equal(t, expectedSummary, calculateSummary(transactions))
@@ -153,21 +352,30 @@ func TestCalculateSummary(t *testing.T) {
 	t.Run("Mixed income and expense transactions", func(t *testing.T) {
 		// Test case 3: Mixed income and expense transactions
 		transactions := []SummaryTransaction{
-			{TransactionType: "income", TotalAmount: 1000.0},
-			{TransactionType: "expense", TotalAmount: 300.0},
-			{TransactionType: "income", TotalAmount: 500.0},
+			{TransactionType: "income", TotalAmount: money.MustParse("1000")},
+			{TransactionType: "expense", TotalAmount: money.MustParse("300")},
+			{TransactionType: "income", TotalAmount: money.MustParse("500")},
 		}
 		expectedSummary := SummaryResponse{
-			Summary: struct {
-				TotalIncome    float64 `json:"total_income"`
-				TotalExpenses  float64 `json:"total_expenses"`
-				CurrentBalance float64 `json:"current_balance"`
-			}{
-				TotalIncome:    1500.0,
-				TotalExpenses:  300.0,
-				CurrentBalance: 1200.0,
+			Summary: Summary{
+				TotalIncome:    money.MustParse("1500"),
+				TotalExpenses:  money.MustParse("300"),
+				CurrentBalance: money.MustParse("1200"),
 			},
 		}
 		assert.Equal(t, expectedSummary, calculateSummary(transactions))
 	})
+
+	t.Run("Satang amounts do not drift", func(t *testing.T) {
+		transactions := []SummaryTransaction{
+			{TransactionType: "income", TotalAmount: money.MustParse("0.1")},
+			{TransactionType: "income", TotalAmount: money.MustParse("0.2")},
+			{TransactionType: "expense", TotalAmount: money.MustParse("0.3")},
+		}
+
+		got := calculateSummary(transactions)
+
+		assert.Equal(t, money.MustParse("0.3"), got.Summary.TotalIncome)
+		assert.Equal(t, money.MustParse("0"), got.Summary.CurrentBalance)
+	})
 }
@@ -0,0 +1,148 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/fx"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// TagTotal is what came in and went out on the transactions carrying a tag.
+// A transaction with several tags counts towards every one of them.
+type TagTotal struct {
+	Tag     string       `json:"tag"`
+	Income  money.Amount `json:"income"`
+	Expense money.Amount `json:"expense"`
+	Count   int64        `json:"count"`
+}
+
+type TagSummaryResponse struct {
+	Currency string     `json:"currency"`
+	From     string     `json:"from,omitempty"`
+	To       string     `json:"to,omitempty"`
+	Tags     []TagTotal `json:"tags"`
+}
+
+type tagTotal struct {
+	Tag   string
+	Count int64
+	SummaryTransaction
+}
+
+const (
+	tags_stmt = `SELECT g.name, t.transaction_type as tran_type, t.currency, sum(t.amount) as total_amount, count(DISTINCT t.id) as total_count FROM transaction_line t JOIN transaction_tag tt ON tt.transaction_id = t.id JOIN tag g ON g.id = tt.tag_id WHERE t.spender_id = $1 AND t.deleted_at IS NULL` + periodCond + ` group by g.name, t.transaction_type, t.currency`
+)
+
+// GetSpenderTagSummary totals the income and expenses of a spender per tag,
+// in the home currency of the spender. Like the other summaries it counts the
+// share of the spender in split transactions.
+func (h handler) GetSpenderTagSummary(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid spender id")
+	}
+
+	ctx := c.Request().Context()
+
+	home, _, loc, err := h.getSpenderZone(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, "spender not found")
+	}
+	if err != nil {
+		mlog.L(c).Error("query spender error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderTagSummary error")
+	}
+
+	period, err := parsePeriod(c, loc)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+	}
+
+	totals, err := h.getTagTotals(ctx, id, period)
+	if err != nil {
+		mlog.L(c).Error("query tag totals error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderTagSummary error")
+	}
+
+	resp, err := h.newConverter(ctx, home, time.Now()).tags(totals)
+	if errors.Is(err, fx.ErrNoRate) {
+		return c.JSON(http.StatusUnprocessableEntity, Err{Message: err.Error()})
+	}
+	if err != nil {
+		mlog.L(c).Error("convert tag totals error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, "getSpenderTagSummary error")
+	}
+
+	if period.From != nil {
+		resp.From = period.From.Format(dateLayout)
+	}
+	if period.To != nil {
+		resp.To = period.To.AddDate(0, 0, -1).Format(dateLayout)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h handler) getTagTotals(ctx context.Context, id int, p Period) ([]tagTotal, error) {
+	rows, err := h.db.QueryContext(ctx, tags_stmt, append([]any{id}, p.args()...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []tagTotal
+	for rows.Next() {
+		var t tagTotal
+		if err := rows.Scan(&t.Tag, &t.TransactionType, &t.Currency, &t.TotalAmount, &t.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// tags merges the totals of a tag recorded in several currencies, in
+// alphabetical order of the tags.
+func (cv *converter) tags(totals []tagTotal) (TagSummaryResponse, error) {
+	byTag := map[string]*TagTotal{}
+	for _, t := range totals {
+		if t.TransactionType != "income" && t.TransactionType != "expense" {
+			continue
+		}
+		ct, err := cv.convert(t.SummaryTransaction)
+		if err != nil {
+			return TagSummaryResponse{}, err
+		}
+
+		tt, ok := byTag[t.Tag]
+		if !ok {
+			tt = &TagTotal{Tag: t.Tag}
+			byTag[t.Tag] = tt
+		}
+		if t.TransactionType == "income" {
+			tt.Income += ct.TotalAmount
+		} else {
+			tt.Expense += ct.TotalAmount
+		}
+		tt.Count += t.Count
+	}
+
+	resp := TagSummaryResponse{Currency: cv.home, Tags: []TagTotal{}}
+	for _, tt := range byTag {
+		resp.Tags = append(resp.Tags, *tt)
+	}
+	sort.Slice(resp.Tags, func(i, j int) bool { return resp.Tags[i].Tag < resp.Tags[j].Tag })
+	return resp, nil
+}
@@ -1,6 +1,8 @@
 package transaction
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,10 +14,21 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
 
+const existsSpenderStmt = `SELECT EXISTS(SELECT 1 FROM spender WHERE id = $1);`
+
+// spentLog records the expenses reported to the alerter.
+type spentLog []string
+
+func (l *spentLog) Spent(_ context.Context, spenderID int64, category string, date time.Time) {
+	*l = append(*l, fmt.Sprintf("%d %s %s", spenderID, category, date.Format(time.RFC3339)))
+}
+
 func TestCreateTransaction(t *testing.T) {
 
 	t.Run("create transaction fail when bad request body", func(t *testing.T) {
@@ -70,7 +83,7 @@ func TestCreateTransaction(t *testing.T) {
 
 		tr := TransactionRequest{
 			Date:            date,
-			Amount:          1000,
+			Amount:          money.MustParse("1000"),
 			Category:        "Food",
 			TransactionType: "expense",
 			Note:            "Lunch",
@@ -78,11 +91,16 @@ func TestCreateTransaction(t *testing.T) {
 			SpenderID:       1,
 		}
 
-		row := sqlmock.NewRows([]string{"id"}).AddRow(1)
-		mock.ExpectQuery(cStmt).WithArgs(tr.Date, tr.Amount, tr.Category, tr.TransactionType, tr.Note, tr.ImageUrl, tr.SpenderID).WillReturnRows(row)
+		row := sqlmock.NewRows([]string{"id", "currency"}).AddRow(1, "THB")
+		mock.ExpectQuery(existsSpenderStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectBegin()
+		mock.ExpectQuery(cStmt).WithArgs(tr.Date, tr.Amount, tr.Category, tr.TransactionType, tr.Note, tr.ImageUrl, tr.SpenderID, tr.Currency).WillReturnRows(row)
+		mock.ExpectCommit()
 		cfg := config.FeatureFlag{EnableCreateTransaction: true}
 
-		h := New(cfg, db)
+		e.Validator = validate.New(db)
+		var spent spentLog
+		h := New(cfg, db).WithAlerter(&spent)
 
 		err = h.Create(c)
 
@@ -103,7 +121,68 @@ func TestCreateTransaction(t *testing.T) {
 			TransactionType: tr.TransactionType,
 			Note:            tr.Note,
 			ImageUrl:        tr.ImageUrl,
+			SpenderID:       &tr.SpenderID,
+			Currency:        "THB",
 		}, got)
+		assert.Equal(t, spentLog{"1 Food 2024-04-30T09:00:00Z"}, spent)
+	})
+	t.Run("create transaction fail when request is invalid", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{
+			"amount": -5,
+			"category": "",
+			"transaction_type": "banana",
+			"spender_id": 42
+		}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectQuery(existsSpenderStmt).WithArgs(int64(42)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		e.Validator = validate.New(db)
+
+		h := New(config.FeatureFlag{EnableCreateTransaction: true}, db)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message":"validation failed","errors":[
+			{"field":"date","message":"is required"},
+			{"field":"amount","message":"must be greater than 0"},
+			{"field":"category","message":"is required"},
+			{"field":"transaction_type","message":"must be one of: income, expense"},
+			{"field":"spender_id","message":"does not exist"}
+		]}`, rec.Body.String())
+	})
+	t.Run("create transaction fail when amount does not fit the column", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{
+			"date": "2024-04-30T09:00:00.000Z",
+			"amount": 100000000,
+			"category": "Food",
+			"transaction_type": "expense",
+			"spender_id": 1
+		}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		e.Validator = validate.New(nil)
+
+		h := New(config.FeatureFlag{EnableCreateTransaction: true}, nil)
+		err := h.Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message":"validation failed","errors":[
+			{"field":"amount","message":"must be at most 99999999.99"}
+		]}`, rec.Body.String())
 	})
 	t.Run("create transaction fail when feature toggle is disable", func(t *testing.T) {
 		e := echo.New()
@@ -162,7 +241,7 @@ func TestCreateTransaction(t *testing.T) {
 
 		tr := TransactionRequest{
 			Date:            date,
-			Amount:          1000,
+			Amount:          money.MustParse("1000"),
 			Category:        "Food",
 			TransactionType: "expense",
 			Note:            "Lunch",
@@ -170,7 +249,11 @@ func TestCreateTransaction(t *testing.T) {
 			SpenderID:       1,
 		}
 
-		mock.ExpectQuery(cStmt).WithArgs(tr.Date, tr.Amount, tr.Category, tr.TransactionType, tr.Note, tr.ImageUrl, tr.SpenderID).WillReturnError(fmt.Errorf("query row error"))
+		mock.ExpectQuery(existsSpenderStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectBegin()
+		mock.ExpectQuery(cStmt).WithArgs(tr.Date, tr.Amount, tr.Category, tr.TransactionType, tr.Note, tr.ImageUrl, tr.SpenderID, tr.Currency).WillReturnError(fmt.Errorf("query row error"))
+		mock.ExpectRollback()
+		e.Validator = validate.New(db)
 		h := New(cfg, db)
 		err = h.Create(c)
 
@@ -238,7 +321,7 @@ func TestUpdateTransaction(t *testing.T) {
 
 		tr := TransactionRequest{
 			Date:            date,
-			Amount:          1000,
+			Amount:          money.MustParse("1000"),
 			Category:        "Food",
 			TransactionType: "expense",
 			Note:            "Lunch",
@@ -246,11 +329,19 @@ func TestUpdateTransaction(t *testing.T) {
 			SpenderID:       1,
 		}
 
-		row := sqlmock.NewRows([]string{"id"}).AddRow(1)
-		mock.ExpectQuery(uStmt).WithArgs(tr.Date, tr.Amount, tr.Category, tr.TransactionType, tr.Note, tr.ImageUrl, tr.SpenderID).WillReturnRows(row)
+		row := sqlmock.NewRows([]string{"id", "version", "currency"}).AddRow(1, 2, "THB")
+		mock.ExpectQuery(existsSpenderStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectBegin()
+		mock.ExpectQuery(uStmt).WithArgs(tr.Date, tr.Amount, tr.Category, tr.TransactionType, tr.Note, tr.ImageUrl, tr.SpenderID, tr.Currency, 1, nil).WillReturnRows(row)
+		mock.ExpectExec(deleteSplitsStmt).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(deleteTagsStmt).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(mirrorStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"transaction_type", "spender_id"}))
+		mock.ExpectCommit()
 		cfg := config.FeatureFlag{EnableUpdateTransaction: true}
 
-		h := New(cfg, db)
+		e.Validator = validate.New(db)
+		var spent spentLog
+		h := New(cfg, db).WithAlerter(&spent)
 
 		err = h.Update(c)
 
@@ -271,7 +362,152 @@ func TestUpdateTransaction(t *testing.T) {
 			TransactionType: tr.TransactionType,
 			Note:            tr.Note,
 			ImageUrl:        tr.ImageUrl,
+			SpenderID:       &tr.SpenderID,
+			Currency:        "THB",
 		}, got)
+		assert.Equal(t, spentLog{"1 Food 2024-04-30T09:00:00Z"}, spent)
+	})
+	t.Run("update transaction fail when version does not match", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{
+			"date": "2024-04-30T09:00:00.000Z",
+			"amount": 1000,
+			"category": "Food",
+			"transaction_type": "expense",
+			"note": "Lunch",
+			"image_url": "https://example.com/image1.jpg",
+			"spender_id": 1
+		}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("If-Match", `"1"`)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectQuery(existsSpenderStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectBegin()
+		mock.ExpectQuery(uStmt).WillReturnRows(sqlmock.NewRows([]string{"id", "version"}))
+		mock.ExpectQuery(versionStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+		e.Validator = validate.New(db)
+		h := New(config.FeatureFlag{EnableUpdateTransaction: true}, db)
+		err := h.Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	})
+	t.Run("update transaction fail when transaction does not exist", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{
+			"date": "2024-04-30T09:00:00.000Z",
+			"amount": 1000,
+			"category": "Food",
+			"transaction_type": "expense",
+			"note": "Lunch",
+			"image_url": "https://example.com/image1.jpg",
+			"spender_id": 1
+		}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectQuery(existsSpenderStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectBegin()
+		mock.ExpectQuery(uStmt).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		e.Validator = validate.New(db)
+		h := New(config.FeatureFlag{EnableUpdateTransaction: true}, db)
+		err := h.Update(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"message":"transaction not found"}`, rec.Body.String())
+	})
+}
+
+func TestGetTransactionByID(t *testing.T) {
+	t.Run("get transaction successfully", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("api/v1/transactions/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+		row := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}).
+			AddRow(1, date, 1000.00, "Food", "expense", "Lunch", "https://example.com/image1.jpg", 7, "THB", 4)
+		mock.ExpectQuery(getStmt).WithArgs(1).WillReturnRows(row)
+		mock.ExpectQuery(splitsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"category", "amount", "spender_id"}))
+		mock.ExpectQuery(tagsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"4"`, rec.Header().Get("ETag"))
+		assert.JSONEq(t, `{"id":1,"date":"2024-04-30T09:00:00Z","amount":1000,"category":"Food","transaction_type":"expense","note":"Lunch","image_url":"https://example.com/image1.jpg","spender_id":7,"currency":"THB"}`, rec.Body.String())
+	})
+
+	t.Run("get transaction fail when transaction does not exist", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("99")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectQuery(getStmt).WithArgs(99).WillReturnError(sql.ErrNoRows)
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"message":"transaction not found"}`, rec.Body.String())
+	})
+
+	t.Run("get transaction fail when id is invalid", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("abc")
+
+		h := New(config.FeatureFlag{}, nil)
+		err := h.GetByID(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 }
 
@@ -293,15 +529,43 @@ func TestGetTransactionById(t *testing.T) {
 		date1, _ := time.Parse(time.RFC3339, "2022-01-01T12:00:00Z")
 		date2, _ := time.Parse(time.RFC3339, "2022-01-02T12:00:00Z")
 
-		rows := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url"}).
-			AddRow(1, date1, 100.00, "groceries", "expense", "Weekly groceries", "http://example.com/receipt1.jpg").
-			AddRow(2, date2, 150.00, "electronics", "expense", "Gadget purchase", "http://example.com/receipt2.jpg")
-		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url FROM transaction WHERE spender_id = \$1`).WithArgs(1).WillReturnRows(rows)
+		rows := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency"}).
+			AddRow(1, date1, 100.00, "groceries", "expense", "Weekly groceries", "http://example.com/receipt1.jpg", 1, "THB").
+			AddRow(2, date2, 150.00, "electronics", "expense", "Gadget purchase", "http://example.com/receipt2.jpg", 1, "THB")
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL AND spender_id = \$1 ORDER BY date DESC, id DESC$`).WithArgs(1).WillReturnRows(rows)
+		mock.ExpectQuery(`FROM transaction_split`).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(`FROM transaction_tag tt`).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
 
 		h := New(config.FeatureFlag{}, db)
 		if assert.NoError(t, h.GetTransactionById(c)) {
 			assert.Equal(t, http.StatusOK, rec.Code)
-			assert.JSONEq(t, `{"transactions":[{"id":1,"date":"2022-01-01T12:00:00Z","amount":100.00,"category":"groceries","transaction_type":"expense","note":"Weekly groceries","image_url":"http://example.com/receipt1.jpg"},{"id":2,"date":"2022-01-02T12:00:00Z","amount":150.00,"category":"electronics","transaction_type":"expense","note":"Gadget purchase","image_url":"http://example.com/receipt2.jpg"}]}`, rec.Body.String())
+			assert.JSONEq(t, `{"transactions":[{"id":1,"date":"2022-01-01T12:00:00Z","amount":100.00,"category":"groceries","transaction_type":"expense","note":"Weekly groceries","image_url":"http://example.com/receipt1.jpg","spender_id":1,"currency":"THB"},{"id":2,"date":"2022-01-02T12:00:00Z","amount":150.00,"category":"electronics","transaction_type":"expense","note":"Gadget purchase","image_url":"http://example.com/receipt2.jpg","spender_id":1,"currency":"THB"}]}`, rec.Body.String())
+		}
+	})
+
+	t.Run("retrieve one page of transactions by spender ID when a limit is given", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/transactions/1?limit=1", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+		defer db.Close()
+
+		date1, _ := time.Parse(time.RFC3339, "2022-01-01T12:00:00Z")
+		rows := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency"}).
+			AddRow(1, date1, 100.00, "groceries", "expense", "Weekly groceries", "http://example.com/receipt1.jpg", 1, "THB")
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM transaction WHERE deleted_at IS NULL AND spender_id = \$1`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL AND spender_id = \$1`).WithArgs(1, 1, 0).WillReturnRows(rows)
+		mock.ExpectQuery(`FROM transaction_split`).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(`FROM transaction_tag tt`).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		if assert.NoError(t, h.GetTransactionById(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, `{"transactions":[{"id":1,"date":"2022-01-01T12:00:00Z","amount":100.00,"category":"groceries","transaction_type":"expense","note":"Weekly groceries","image_url":"http://example.com/receipt1.jpg","spender_id":1,"currency":"THB"}],
+				"pagination":{"current_page":1,"total_pages":2,"per_page":1,"total_items":2}}`, rec.Body.String())
 		}
 	})
 
@@ -316,7 +580,7 @@ func TestGetTransactionById(t *testing.T) {
 		defer db.Close()
 
 		// Configure the mock to return an error for the query
-		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url FROM transaction WHERE spender_id = \$1`).WithArgs(1).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL AND spender_id = \$1`).WithArgs(1).WillReturnError(assert.AnError)
 
 		h := New(config.FeatureFlag{}, db)
 		err := h.GetTransactionById(c)
@@ -343,17 +607,74 @@ func TestGetAllTransaction(t *testing.T) {
 		date1, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
 		date2, _ := time.Parse(time.RFC3339, "2024-04-29T19:00:00Z")
 
-		rows := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url"}).
-			AddRow(1, date1, 1000.00, "Food", "expense", "Lunch", "https://example.com/image1.jpg").
-			AddRow(2, date2, 2000.00, "Transport", "income", "Salary", "https://example.com/image2.jpg")
-		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url FROM transaction`).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT COUNT(*) FROM transaction WHERE deleted_at IS NULL`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		rows := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency"}).
+			AddRow(1, date1, 1000.00, "Food", "expense", "Lunch", "https://example.com/image1.jpg", 1, "THB").
+			AddRow(2, date2, 2000.00, "Transport", "income", "Salary", "https://example.com/image2.jpg", 1, "THB")
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL ORDER BY date DESC, id DESC LIMIT $1 OFFSET $2`).
+			WithArgs(10, 0).WillReturnRows(rows)
+		mock.ExpectQuery(listSplitsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(listTagsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetAll(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"transactions":[{"id":1,"date":"2024-04-30T09:00:00Z","amount":1000.00,"category":"Food","transaction_type":"expense","note":"Lunch","image_url":"https://example.com/image1.jpg","spender_id":1,"currency":"THB"},{"id":2,"date":"2024-04-29T19:00:00Z","amount":2000.00,"category":"Transport","transaction_type":"income","note":"Salary","image_url":"https://example.com/image2.jpg","spender_id":1,"currency":"THB"}],
+			"pagination":{"current_page":1,"total_pages":1,"per_page":10,"total_items":2}}`, rec.Body.String())
+	})
+
+	t.Run("get all transaction with paging, filtering and sorting", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/?page=2&limit=1&date=2024-04-30&amount_min=500&category=Food&transaction_type=expense&sort=-amount", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		from, _ := time.Parse(time.RFC3339, "2024-04-30T00:00:00Z")
+		to, _ := time.Parse(time.RFC3339, "2024-05-01T00:00:00Z")
+		date1, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+
+		where := ` WHERE deleted_at IS NULL AND date >= $1 AND date < $2 AND amount >= $3 AND category = $4 AND transaction_type = $5`
+		mock.ExpectQuery(`SELECT COUNT(*) FROM transaction`+where).
+			WithArgs(from, to, money.MustParse("500"), "Food", "expense").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		rows := sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency"}).
+			AddRow(1, date1, 1000.00, "Food", "expense", "Lunch", "https://example.com/image1.jpg", 1, "THB")
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction`+where+` ORDER BY amount DESC, id ASC LIMIT $6 OFFSET $7`).
+			WithArgs(from, to, money.MustParse("500"), "Food", "expense", 1, 1).WillReturnRows(rows)
+		mock.ExpectQuery(listSplitsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(listTagsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
 
 		h := New(config.FeatureFlag{}, db)
 		err := h.GetAll(c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, rec.Code)
-		assert.JSONEq(t, `{"transactions":[{"id":1,"date":"2024-04-30T09:00:00Z","amount":1000.00,"category":"Food","transaction_type":"expense","note":"Lunch","image_url":"https://example.com/image1.jpg"},{"id":2,"date":"2024-04-29T19:00:00Z","amount":2000.00,"category":"Transport","transaction_type":"income","note":"Salary","image_url":"https://example.com/image2.jpg"}]}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.JSONEq(t, `{"transactions":[{"id":1,"date":"2024-04-30T09:00:00Z","amount":1000.00,"category":"Food","transaction_type":"expense","note":"Lunch","image_url":"https://example.com/image1.jpg","spender_id":1,"currency":"THB"}],
+			"pagination":{"current_page":2,"total_pages":3,"per_page":1,"total_items":3}}`, rec.Body.String())
+	})
+
+	t.Run("get all transaction fail when query parameter is invalid", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/?sort=password", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{}, nil)
+		err := h.GetAll(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid query parameter: sort")
 	})
 
 	t.Run("get all transaction failed on database", func(t *testing.T) {
@@ -367,7 +688,7 @@ func TestGetAllTransaction(t *testing.T) {
 		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		defer db.Close()
 
-		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url FROM transaction`).WillReturnError(assert.AnError)
+		mock.ExpectQuery(`SELECT COUNT(*) FROM transaction WHERE deleted_at IS NULL`).WillReturnError(assert.AnError)
 
 		h := New(config.FeatureFlag{}, db)
 		err := h.GetAll(c)
@@ -0,0 +1,255 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+)
+
+const keysetOrder = "date DESC, id DESC"
+
+// runningSelectStmt adds the balance after every transaction, in the currency
+// of the transaction. The balance runs over the share of the spender, numbered
+// $1, in every transaction whatever the filters of the listing, so split lines
+// on transactions of others count just as they do in the summaries.
+const runningSelectStmt = `SELECT ` + selectColumns + `, running_balance FROM (SELECT t.*, SUM(COALESCE(l.share, 0)) OVER (PARTITION BY t.currency ORDER BY t.date, t.id) AS running_balance FROM transaction t LEFT JOIN (SELECT id, SUM(CASE transaction_type WHEN 'income' THEN amount WHEN 'expense' THEN -amount ELSE 0 END) AS share FROM transaction_line WHERE spender_id = $1 GROUP BY id) l ON l.id = t.id WHERE (t.spender_id = $1 OR l.id IS NOT NULL) AND t.deleted_at IS NULL AND t.date IS NOT NULL) AS transaction`
+
+// list returns one page of the transactions matching the request's query
+// parameters. The request is paged by keyset when it carries a cursor
+// parameter (an empty cursor means the first page) and by offset otherwise.
+// The list of one spender predates paging, it returns every transaction
+// unless the request asks for a page, limit or cursor.
+func (h handler) list(c echo.Context, spenderID int64) (echo.Map, error) {
+	ctx := c.Request().Context()
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return nil, err
+	}
+	filter.SpenderID = spenderID
+
+	page, err := parsePage(c)
+	if err != nil {
+		return nil, err
+	}
+
+	running, err := parseRunningBalance(c, spenderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := c.QueryParams()["cursor"]; ok {
+		if c.QueryParam("sort") != "" {
+			return nil, queryError{"sort"}
+		}
+		return h.listByCursor(ctx, filter, c.QueryParam("cursor"), page.Limit, running)
+	}
+
+	order, err := parseSort(c.QueryParam("sort"))
+	if err != nil {
+		return nil, err
+	}
+	if running && c.QueryParam("sort") == "" {
+		// a running balance reads best oldest first, like a bank statement
+		order = "date ASC, id ASC"
+	}
+	if spenderID != 0 && !paged(c) {
+		return h.listAll(ctx, filter, order, running)
+	}
+	return h.listByPage(ctx, filter, page, order, running)
+}
+
+func paged(c echo.Context) bool {
+	q := c.QueryParams()
+	return q.Has("page") || q.Has("limit") || q.Has("cursor")
+}
+
+// parseRunningBalance reads the running_balance option, which only a
+// listing of one spender supports.
+func parseRunningBalance(c echo.Context, spenderID int64) (bool, error) {
+	v := c.QueryParam("running_balance")
+	if v == "" {
+		return false, nil
+	}
+	running, err := strconv.ParseBool(v)
+	if err != nil || (running && spenderID == 0) {
+		return false, queryError{"running_balance"}
+	}
+	return running, nil
+}
+
+// source returns the SELECT a listing reads from and narrows where to the
+// rows it can return.
+func source(where string, running bool) (string, string) {
+	if !running {
+		return selectStmt, where
+	}
+	// rows without a date have no place in the running balance
+	return runningSelectStmt, and(where, "date IS NOT NULL")
+}
+
+func (h handler) listByPage(ctx context.Context, filter Filter, page Page, order string, running bool) (echo.Map, error) {
+	where, args := filter.where()
+	stmt, where := source(where, running)
+
+	var total int
+	if err := h.db.QueryRowContext(ctx, countStmt+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("%s%s ORDER BY %s LIMIT $%d OFFSET $%d", stmt, where, order, len(args)+1, len(args)+2)
+	rows, err := h.db.QueryContext(ctx, query, append(args, page.Limit, page.offset())...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tRs, err := scanTransactions(rows, running)
+	if err != nil {
+		return nil, err
+	}
+	if err := fillSplits(ctx, h.db, tRs); err != nil {
+		return nil, err
+	}
+	if err := fillTags(ctx, h.db, tRs); err != nil {
+		return nil, err
+	}
+
+	return echo.Map{
+		"transactions": tRs,
+		"pagination": Pagination{
+			CurrentPage: page.Page,
+			TotalPages:  totalPages(total, page.Limit),
+			PerPage:     page.Limit,
+			TotalItems:  total,
+		},
+	}, nil
+}
+
+func (h handler) listAll(ctx context.Context, filter Filter, order string, running bool) (echo.Map, error) {
+	where, args := filter.where()
+	stmt, where := source(where, running)
+
+	rows, err := h.db.QueryContext(ctx, stmt+where+" ORDER BY "+order, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tRs, err := scanTransactions(rows, running)
+	if err != nil {
+		return nil, err
+	}
+	if err := fillSplits(ctx, h.db, tRs); err != nil {
+		return nil, err
+	}
+	if err := fillTags(ctx, h.db, tRs); err != nil {
+		return nil, err
+	}
+
+	return echo.Map{"transactions": tRs}, nil
+}
+
+// listByCursor seeks past the cursor row instead of counting an offset, so
+// rows inserted while a client is scrolling neither shift nor repeat a page.
+func (h handler) listByCursor(ctx context.Context, filter Filter, token string, limit int, running bool) (echo.Map, error) {
+	var cur Cursor
+	if token != "" {
+		var err error
+		if cur, err = decodeCursor(token); err != nil {
+			return nil, err
+		}
+	}
+
+	where, args := filter.where()
+	stmt, where := source(where, running)
+	// rows without a date have no position in the keyset ordering
+	if !running {
+		where = and(where, "date IS NOT NULL")
+	}
+	order := keysetOrder
+	if token != "" {
+		args = append(args, cur.Date, cur.ID)
+		if cur.Prev {
+			where = and(where, fmt.Sprintf("(date, id) > ($%d, $%d)", len(args)-1, len(args)))
+			order = "date ASC, id ASC"
+		} else {
+			where = and(where, fmt.Sprintf("(date, id) < ($%d, $%d)", len(args)-1, len(args)))
+		}
+	}
+
+	// fetch one extra row to learn whether there is anything beyond this page
+	query := fmt.Sprintf("%s%s ORDER BY %s LIMIT $%d", stmt, where, order, len(args)+1)
+	rows, err := h.db.QueryContext(ctx, query, append(args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tRs, err := scanTransactions(rows, running)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(tRs) > limit
+	if hasMore {
+		tRs = tRs[:limit]
+	}
+	if cur.Prev {
+		slices.Reverse(tRs)
+	}
+	if err := fillSplits(ctx, h.db, tRs); err != nil {
+		return nil, err
+	}
+	if err := fillTags(ctx, h.db, tRs); err != nil {
+		return nil, err
+	}
+
+	p := CursorPagination{PerPage: limit}
+	if len(tRs) > 0 {
+		first, last := tRs[0], tRs[len(tRs)-1]
+		if (cur.Prev && hasMore) || (token != "" && !cur.Prev) {
+			p.PrevCursor = Cursor{Date: *first.Date, ID: first.ID, Prev: true}.Encode()
+		}
+		if cur.Prev || hasMore {
+			p.NextCursor = Cursor{Date: *last.Date, ID: last.ID}.Encode()
+		}
+	}
+
+	return echo.Map{
+		"transactions": tRs,
+		"cursor":       p,
+	}, nil
+}
+
+func scanTransactions(rows *sql.Rows, running bool) ([]TransactionResponse, error) {
+	tRs := []TransactionResponse{}
+	for rows.Next() {
+		var tR TransactionResponse
+		dest := tR.fields()
+		if running {
+			tR.RunningBalance = new(money.Amount)
+			dest = append(dest, tR.RunningBalance)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		tRs = append(tRs, tR)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tRs, nil
+}
+
+func and(where, cond string) string {
+	if where == "" {
+		return " WHERE " + cond
+	}
+	return where + " AND " + cond
+}
@@ -0,0 +1,195 @@
+package transaction
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type cursorPage struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	Cursor       CursorPagination      `json:"cursor"`
+}
+
+func TestListByCursor(t *testing.T) {
+	date1, _ := time.Parse(time.RFC3339, "2024-04-30T09:00:00Z")
+	date2, _ := time.Parse(time.RFC3339, "2024-04-29T19:00:00Z")
+	date3, _ := time.Parse(time.RFC3339, "2024-04-28T08:00:00Z")
+	columns := []string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency"}
+
+	t.Run("first page returns next cursor only", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/?cursor=&limit=2", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(3, date1, 100.00, "Food", "expense", "", "", 1, "THB").
+			AddRow(2, date2, 200.00, "Food", "expense", "", "", 1, "THB").
+			AddRow(1, date3, 300.00, "Food", "expense", "", "", 1, "THB")
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL AND date IS NOT NULL ORDER BY date DESC, id DESC LIMIT $1`).
+			WithArgs(3).WillReturnRows(rows)
+		mock.ExpectQuery(listSplitsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}).
+			AddRow(3, "Food", "60.00", nil).AddRow(3, "Drinks", "40.00", 2))
+		mock.ExpectQuery(listTagsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetAll(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var got cursorPage
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Len(t, got.Transactions, 2)
+		assert.Len(t, got.Transactions[0].Splits, 2)
+		assert.Empty(t, got.Cursor.PrevCursor)
+		assert.Equal(t, Cursor{Date: date2, ID: 2}.Encode(), got.Cursor.NextCursor)
+	})
+
+	t.Run("next page seeks past the cursor row of a spender", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		cursor := Cursor{Date: date2, ID: 2}.Encode()
+		req := httptest.NewRequest(http.MethodGet, "/?limit=2&cursor="+cursor, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(1, date3, 300.00, "Food", "expense", "", "", 1, "THB")
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL AND spender_id = $1 AND date IS NOT NULL AND (date, id) < ($2, $3) ORDER BY date DESC, id DESC LIMIT $4`).
+			WithArgs(int64(7), date2, int64(2), 3).WillReturnRows(rows)
+		mock.ExpectQuery(listSplitsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(listTagsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetTransactionById(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var got cursorPage
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Len(t, got.Transactions, 1)
+		assert.Equal(t, Cursor{Date: date3, ID: 1, Prev: true}.Encode(), got.Cursor.PrevCursor)
+		assert.Empty(t, got.Cursor.NextCursor)
+	})
+
+	t.Run("previous page is returned newest first", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		cursor := Cursor{Date: date3, ID: 1, Prev: true}.Encode()
+		req := httptest.NewRequest(http.MethodGet, "/?limit=1&cursor="+cursor, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(2, date2, 200.00, "Food", "expense", "", "", 1, "THB").
+			AddRow(3, date1, 100.00, "Food", "expense", "", "", 1, "THB")
+		mock.ExpectQuery(`SELECT id, date, amount, category, transaction_type, note, image_url, spender_id, currency FROM transaction WHERE deleted_at IS NULL AND date IS NOT NULL AND (date, id) > ($1, $2) ORDER BY date ASC, id ASC LIMIT $3`).
+			WithArgs(date3, int64(1), 2).WillReturnRows(rows)
+		mock.ExpectQuery(listSplitsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(listTagsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetAll(c)
+
+		assert.NoError(t, err)
+
+		var got cursorPage
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, int64(2), got.Transactions[0].ID)
+		assert.Equal(t, Cursor{Date: date2, ID: 2, Prev: true}.Encode(), got.Cursor.PrevCursor)
+		assert.Equal(t, Cursor{Date: date2, ID: 2}.Encode(), got.Cursor.NextCursor)
+	})
+
+	t.Run("sort is rejected in cursor mode", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/?cursor=&sort=amount", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{}, nil)
+		err := h.GetAll(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestListRunningBalance(t *testing.T) {
+	date1, _ := time.Parse(time.RFC3339, "2024-04-28T08:00:00Z")
+	date2, _ := time.Parse(time.RFC3339, "2024-04-29T19:00:00Z")
+	columns := []string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "running_balance"}
+
+	t.Run("rows of a spender carry the balance after them, oldest first", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/?running_balance=true&category=Food", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		rows := sqlmock.NewRows(columns).
+			AddRow(1, date1, "100.00", "Food", "expense", "", "", 7, "THB", "4900.00").
+			AddRow(3, date2, "50.25", "Food", "expense", "", "", 7, "THB", "4849.75")
+		mock.ExpectQuery(runningSelectStmt+` WHERE deleted_at IS NULL AND spender_id = $1 AND category = $2 AND date IS NOT NULL ORDER BY date ASC, id ASC`).
+			WithArgs(int64(7), "Food").WillReturnRows(rows)
+		mock.ExpectQuery(listSplitsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "category", "amount", "spender_id"}))
+		mock.ExpectQuery(listTagsStmt).WillReturnRows(sqlmock.NewRows([]string{"transaction_id", "name"}))
+
+		h := New(config.FeatureFlag{}, db)
+		err := h.GetTransactionById(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"running_balance":4900`)
+		assert.Contains(t, rec.Body.String(), `"running_balance":4849.75`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("running balance needs a spender", func(t *testing.T) {
+		e := echo.New()
+		defer e.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/?running_balance=true", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		h := New(config.FeatureFlag{}, nil)
+		err := h.GetAll(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"message": "invalid query parameter: running_balance"}`, rec.Body.String())
+	})
+}
@@ -0,0 +1,152 @@
+package transaction
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// groupByUnits is the whitelist of bucket sizes, the values are the units
+// of date_trunc.
+var groupByUnits = map[string]string{
+	"day":   "day",
+	"week":  "week",
+	"month": "month",
+	"year":  "year",
+}
+
+// maxBuckets caps the series of a summary, a wider range needs a coarser
+// group_by.
+const maxBuckets = 1000
+
+// Period narrows a summary to [From, To) and optionally splits it into
+// buckets. Dates are read in the spender's timezone. ExcludeTransfers
+// leaves out the money moved between spenders.
+type Period struct {
+	From             *time.Time
+	To               *time.Time
+	GroupBy          string
+	ExcludeTransfers bool
+	loc              *time.Location
+}
+
+type Bucket struct {
+	Start string `json:"start"`
+	Summary
+}
+
+func parsePeriod(c echo.Context, loc *time.Location) (Period, error) {
+	p := Period{loc: loc}
+
+	if v := c.QueryParam("from"); v != "" {
+		d, err := time.ParseInLocation(dateLayout, v, loc)
+		if err != nil {
+			return Period{}, queryError{"from"}
+		}
+		p.From = &d
+	}
+	if v := c.QueryParam("to"); v != "" {
+		d, err := time.ParseInLocation(dateLayout, v, loc)
+		if err != nil {
+			return Period{}, queryError{"to"}
+		}
+		// to is inclusive, so the upper bound is the start of the next day
+		to := d.AddDate(0, 0, 1)
+		if p.From != nil && !to.After(*p.From) {
+			return Period{}, queryError{"to"}
+		}
+		p.To = &to
+	}
+	if v := c.QueryParam("group_by"); v != "" {
+		unit, ok := groupByUnits[v]
+		if !ok {
+			return Period{}, queryError{"group_by"}
+		}
+		p.GroupBy = unit
+	}
+	if p.GroupBy != "" && p.From != nil && p.To != nil {
+		if _, err := p.buckets(*p.From, *p.To); err != nil {
+			return Period{}, err
+		}
+	}
+	if v := c.QueryParam("exclude_transfers"); v != "" {
+		exclude, err := strconv.ParseBool(v)
+		if err != nil {
+			return Period{}, queryError{"exclude_transfers"}
+		}
+		p.ExcludeTransfers = exclude
+	}
+
+	return p, nil
+}
+
+// allTime reports whether the period covers every transaction.
+func (p Period) allTime() bool {
+	return p.From == nil && p.To == nil
+}
+
+// args are the bounds as query arguments, nil when open, and whether to
+// leave out transfers.
+func (p Period) args() []any {
+	args := []any{nil, nil, p.ExcludeTransfers}
+	if p.From != nil {
+		args[0] = *p.From
+	}
+	if p.To != nil {
+		args[1] = *p.To
+	}
+	return args
+}
+
+// truncate returns the start of the bucket t falls in, weeks start on
+// Monday like date_trunc.
+func (p Period) truncate(t time.Time) time.Time {
+	t = t.In(p.loc)
+	y, m, d := t.Date()
+	switch p.GroupBy {
+	case "week":
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, p.loc)
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, p.loc)
+	case "year":
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, p.loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, p.loc)
+	}
+}
+
+func (p Period) next(t time.Time) time.Time {
+	switch p.GroupBy {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// buckets lists the start of every bucket from first to last, so a chart
+// gets a zero bucket rather than a gap for a quiet period. The bounds of
+// the period take precedence over the first and last bucket seen. There
+// may be no more than maxBuckets of them.
+func (p Period) buckets(first, last time.Time) ([]time.Time, error) {
+	if p.From != nil {
+		first = *p.From
+	}
+	if p.To != nil {
+		last = p.To.Add(-time.Nanosecond)
+	}
+
+	var starts []time.Time
+	for b := p.truncate(first); !b.After(last); b = p.next(b) {
+		if len(starts) == maxBuckets {
+			return nil, queryError{"group_by"}
+		}
+		starts = append(starts, b)
+	}
+	return starts, nil
+}
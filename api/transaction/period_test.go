@@ -0,0 +1,92 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePeriod(t *testing.T) {
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	newContext := func(query string) echo.Context {
+		req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+
+	t.Run("dates are read in the spender's timezone", func(t *testing.T) {
+		p, err := parsePeriod(newContext("from=2024-05-01&to=2024-05-31&group_by=week"), bkk)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 4, 30, 17, 0, 0, 0, time.UTC), p.From.UTC())
+		assert.Equal(t, time.Date(2024, 5, 31, 17, 0, 0, 0, time.UTC), p.To.UTC())
+		assert.Equal(t, "week", p.GroupBy)
+		assert.False(t, p.allTime())
+	})
+
+	t.Run("no parameters is all time", func(t *testing.T) {
+		p, err := parsePeriod(newContext(""), bkk)
+
+		assert.NoError(t, err)
+		assert.True(t, p.allTime())
+		assert.Equal(t, []any{nil, nil, false}, p.args())
+	})
+
+	t.Run("transfers can be left out", func(t *testing.T) {
+		p, err := parsePeriod(newContext("exclude_transfers=true"), bkk)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []any{nil, nil, true}, p.args())
+	})
+
+	for _, query := range []string{"from=yesterday", "to=2024-13-01", "from=2024-05-02&to=2024-05-01", "group_by=hour", "exclude_transfers=maybe", "from=0001-01-01&to=9999-12-31&group_by=day"} {
+		t.Run("reject "+query, func(t *testing.T) {
+			_, err := parsePeriod(newContext(query), bkk)
+
+			assert.ErrorAs(t, err, &queryError{})
+		})
+	}
+}
+
+func TestPeriodBuckets(t *testing.T) {
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, bkk) }
+	format := func(starts []time.Time, err error) []string {
+		assert.NoError(t, err)
+		var s []string
+		for _, t := range starts {
+			s = append(s, t.Format(dateLayout))
+		}
+		return s
+	}
+
+	t.Run("weeks start on monday", func(t *testing.T) {
+		p := Period{GroupBy: "week", loc: bkk}
+
+		assert.Equal(t, []string{"2024-04-29", "2024-05-06", "2024-05-13"}, format(p.buckets(day(2024, 5, 1), day(2024, 5, 13))))
+	})
+
+	t.Run("bounds of the period fill the edges", func(t *testing.T) {
+		from, to := day(2024, 1, 15), day(2024, 4, 1)
+		p := Period{From: &from, To: &to, GroupBy: "month", loc: bkk}
+
+		assert.Equal(t, []string{"2024-01-01", "2024-02-01", "2024-03-01"}, format(p.buckets(day(2024, 2, 10), day(2024, 2, 10))))
+	})
+
+	t.Run("too many buckets for the data", func(t *testing.T) {
+		p := Period{GroupBy: "day", loc: bkk}
+
+		_, err := p.buckets(day(1, 1, 1), day(2024, 5, 1))
+
+		assert.ErrorAs(t, err, &queryError{})
+	})
+
+	t.Run("a UTC instant lands in the local day", func(t *testing.T) {
+		p := Period{GroupBy: "day", loc: bkk}
+
+		assert.Equal(t, day(2024, 5, 2), p.truncate(time.Date(2024, 5, 1, 18, 30, 0, 0, time.UTC)))
+	})
+}
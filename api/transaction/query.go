@@ -0,0 +1,235 @@
+package transaction
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPage  = 1
+	defaultLimit = 10
+	maxLimit     = 100
+	dateLayout   = "2006-01-02"
+)
+
+type Pagination struct {
+	CurrentPage int `json:"current_page"`
+	TotalPages  int `json:"total_pages"`
+	PerPage     int `json:"per_page"`
+	TotalItems  int `json:"total_items"`
+}
+
+// Filter holds the optional conditions of a transaction listing, every zero
+// value means "do not filter on it".
+type Filter struct {
+	SpenderID       int64
+	DateFrom        *time.Time
+	DateTo          *time.Time
+	Amount          *money.Amount
+	AmountMin       *money.Amount
+	AmountMax       *money.Amount
+	Category        string
+	TransactionType string
+	// Tags keeps the transactions carrying any of the tags, or all of them
+	// when AllTags is set.
+	Tags    []string
+	AllTags bool
+}
+
+type Page struct {
+	Page  int
+	Limit int
+}
+
+func (p Page) offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// sortColumns is the whitelist of columns a client may sort on.
+var sortColumns = map[string]string{
+	"id":               "id",
+	"date":             "date",
+	"amount":           "amount",
+	"category":         "category",
+	"transaction_type": "transaction_type",
+}
+
+type queryError struct {
+	param string
+}
+
+func (e queryError) Error() string {
+	return "invalid query parameter: " + e.param
+}
+
+func parseFilter(c echo.Context) (Filter, error) {
+	var f Filter
+
+	if v := c.QueryParam("date"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return Filter{}, queryError{"date"}
+		}
+		to := d.AddDate(0, 0, 1)
+		f.DateFrom, f.DateTo = &d, &to
+	}
+	if v := c.QueryParam("date_from"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return Filter{}, queryError{"date_from"}
+		}
+		f.DateFrom = &d
+	}
+	if v := c.QueryParam("date_to"); v != "" {
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return Filter{}, queryError{"date_to"}
+		}
+		// date_to is inclusive, so the upper bound is the start of the next day
+		to := d.AddDate(0, 0, 1)
+		f.DateTo = &to
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **money.Amount
+	}{
+		{"amount", &f.Amount},
+		{"amount_min", &f.AmountMin},
+		{"amount_max", &f.AmountMax},
+	} {
+		v := c.QueryParam(p.name)
+		if v == "" {
+			continue
+		}
+		a, err := money.Parse(v)
+		if err != nil {
+			return Filter{}, queryError{p.name}
+		}
+		*p.dst = &a
+	}
+
+	f.Category = c.QueryParam("category")
+	f.TransactionType = c.QueryParam("transaction_type")
+
+	f.Tags = normalizeTags(c.QueryParams()["tag"])
+	switch c.QueryParam("tag_match") {
+	case "", "any":
+	case "all":
+		f.AllTags = true
+	default:
+		return Filter{}, queryError{"tag_match"}
+	}
+
+	return f, nil
+}
+
+func parsePage(c echo.Context) (Page, error) {
+	p := Page{Page: defaultPage, Limit: defaultLimit}
+
+	if v := c.QueryParam("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return Page{}, queryError{"page"}
+		}
+		p.Page = page
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return Page{}, queryError{"limit"}
+		}
+		p.Limit = limit
+	}
+
+	return p, nil
+}
+
+// parseSort turns "sort=-date,amount" into an ORDER BY list. The id is always
+// appended as a tie breaker so that pages are stable.
+func parseSort(v string) (string, error) {
+	if v == "" {
+		return "date DESC, id DESC", nil
+	}
+
+	var orders []string
+	hasID := false
+	for _, field := range strings.Split(v, ",") {
+		dir := "ASC"
+		if strings.HasPrefix(field, "-") {
+			dir = "DESC"
+			field = field[1:]
+		}
+		col, ok := sortColumns[field]
+		if !ok {
+			return "", queryError{"sort"}
+		}
+		if col == "id" {
+			hasID = true
+		}
+		orders = append(orders, col+" "+dir)
+	}
+	if !hasID {
+		orders = append(orders, "id ASC")
+	}
+
+	return strings.Join(orders, ", "), nil
+}
+
+// where builds the WHERE clause of f, numbering the placeholders from $1.
+func (f Filter) where() (string, []any) {
+	// soft-deleted rows never show up in a listing
+	conds := []string{"deleted_at IS NULL"}
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.SpenderID != 0 {
+		add("spender_id = $%d", f.SpenderID)
+	}
+	if f.DateFrom != nil {
+		add("date >= $%d", *f.DateFrom)
+	}
+	if f.DateTo != nil {
+		add("date < $%d", *f.DateTo)
+	}
+	if f.Amount != nil {
+		add("amount = $%d", *f.Amount)
+	}
+	if f.AmountMin != nil {
+		add("amount >= $%d", *f.AmountMin)
+	}
+	if f.AmountMax != nil {
+		add("amount <= $%d", *f.AmountMax)
+	}
+	if f.Category != "" {
+		add("category = $%d", f.Category)
+	}
+	if f.TransactionType != "" {
+		add("transaction_type = $%d", f.TransactionType)
+	}
+	if len(f.Tags) > 0 {
+		tagged := "id IN (SELECT tt.transaction_id FROM transaction_tag tt JOIN tag g ON g.id = tt.tag_id WHERE g.name = ANY($%d)"
+		if f.AllTags {
+			tagged += fmt.Sprintf(" GROUP BY tt.transaction_id HAVING count(*) = %d", len(f.Tags))
+		}
+		add(tagged+")", pq.Array(f.Tags))
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func totalPages(total, limit int) int {
+	if total == 0 {
+		return 0
+	}
+	return (total + limit - 1) / limit
+}
@@ -0,0 +1,91 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Run("date range is inclusive of date_to", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?date_from=2024-04-01&date_to=2024-04-30&amount=1000", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		f, err := parseFilter(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), *f.DateFrom)
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), *f.DateTo)
+		assert.Equal(t, money.MustParse("1000"), *f.Amount)
+	})
+
+	t.Run("invalid date returns error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?date=30-04-2024", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		_, err := parseFilter(c)
+
+		assert.EqualError(t, err, "invalid query parameter: date")
+	})
+
+	t.Run("invalid amount returns error", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/?amount_max=lots", nil)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		_, err := parseFilter(c)
+
+		assert.EqualError(t, err, "invalid query parameter: amount_max")
+	})
+}
+
+func TestParsePage(t *testing.T) {
+	t.Run("default page and limit", func(t *testing.T) {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+		p, err := parsePage(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Page{Page: 1, Limit: 10}, p)
+		assert.Equal(t, 0, p.offset())
+	})
+
+	t.Run("limit above maximum returns error", func(t *testing.T) {
+		e := echo.New()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/?limit=1000", nil), httptest.NewRecorder())
+
+		_, err := parsePage(c)
+
+		assert.EqualError(t, err, "invalid query parameter: limit")
+	})
+}
+
+func TestParseSort(t *testing.T) {
+	t.Run("default sort is newest first", func(t *testing.T) {
+		order, err := parseSort("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "date DESC, id DESC", order)
+	})
+
+	t.Run("id is appended as tie breaker", func(t *testing.T) {
+		order, err := parseSort("category,-date")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "category ASC, date DESC, id ASC", order)
+	})
+
+	t.Run("unknown column returns error", func(t *testing.T) {
+		_, err := parseSort("note; DROP TABLE transaction")
+
+		assert.EqualError(t, err, "invalid query parameter: sort")
+	})
+}
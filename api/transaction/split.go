@@ -0,0 +1,130 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const (
+	splitsStmt       = `SELECT category, amount, spender_id FROM transaction_split WHERE transaction_id = $1 ORDER BY id;`
+	listSplitsStmt   = `SELECT transaction_id, category, amount, spender_id FROM transaction_split WHERE transaction_id = ANY($1) ORDER BY id;`
+	insertSplitStmt  = `INSERT INTO transaction_split (transaction_id, category, amount, spender_id) VALUES ($1, $2, $3, $4);`
+	deleteSplitsStmt = `DELETE FROM transaction_split WHERE transaction_id = $1;`
+)
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// checkSplits makes sure the split lines of a transaction add up to its amount.
+func checkSplits(amount money.Amount, splits []Split) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	var sum money.Amount
+	for _, s := range splits {
+		sum += s.Amount
+	}
+	if sum != amount {
+		return validate.Errors{{Field: "splits", Message: fmt.Sprintf("must add up to the amount %s, not %s", amount, sum)}}
+	}
+	return nil
+}
+
+// validateSplits checks every split line against all of its rules, which a
+// partial validation of the request does not reach, and names the failing
+// fields after their line, e.g. splits[1].category.
+func validateSplits(c echo.Context, splits []Split) error {
+	var errs validate.Errors
+	for i := range splits {
+		err := validate.Request(c, &splits[i])
+		var lineErrs validate.Errors
+		if !errors.As(err, &lineErrs) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, fe := range lineErrs {
+			fe.Field = fmt.Sprintf("splits[%d].%s", i, fe.Field)
+			errs = append(errs, fe)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// replaceSplits swaps the split lines of a transaction for splits.
+func replaceSplits(ctx context.Context, tx *sql.Tx, id int64, splits []Split) error {
+	if _, err := tx.ExecContext(ctx, deleteSplitsStmt, id); err != nil {
+		return err
+	}
+	return insertSplits(ctx, tx, id, splits)
+}
+
+func insertSplits(ctx context.Context, tx *sql.Tx, id int64, splits []Split) error {
+	for _, s := range splits {
+		if _, err := tx.ExecContext(ctx, insertSplitStmt, id, s.Category, s.Amount, s.SpenderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getSplits(ctx context.Context, q queryer, id int64) ([]Split, error) {
+	rows, err := q.QueryContext(ctx, splitsStmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var splits []Split
+	for rows.Next() {
+		var s Split
+		if err := rows.Scan(&s.Category, &s.Amount, &s.SpenderID); err != nil {
+			return nil, err
+		}
+		splits = append(splits, s)
+	}
+	return splits, rows.Err()
+}
+
+// fillSplits loads the split lines of a page of transactions in one query.
+func fillSplits(ctx context.Context, q queryer, tRs []TransactionResponse) error {
+	if len(tRs) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(tRs))
+	byID := make(map[int64]*TransactionResponse, len(tRs))
+	for i := range tRs {
+		ids[i] = tRs[i].ID
+		byID[tRs[i].ID] = &tRs[i]
+	}
+
+	rows, err := q.QueryContext(ctx, listSplitsStmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var s Split
+		if err := rows.Scan(&id, &s.Category, &s.Amount, &s.SpenderID); err != nil {
+			return err
+		}
+		if tR, ok := byID[id]; ok {
+			tR.Splits = append(tR.Splits, s)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,78 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSplitTransaction(t *testing.T) {
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		return e.NewContext(req, rec), rec
+	}
+	cfg := config.FeatureFlag{EnableCreateTransaction: true}
+
+	t.Run("create transaction with its split lines in one go", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date := time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(cStmt).WithArgs(date, money.MustParse("100"), "Supermarket", "expense", "", "", int64(1), "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(5, "THB"))
+		mock.ExpectExec(insertSplitStmt).WithArgs(int64(5), "Groceries", money.MustParse("70"), nil).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(insertSplitStmt).WithArgs(int64(5), "Household", money.MustParse("30"), int64(2)).WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		c, rec := newContext(`{
+			"date": "2024-04-30T09:00:00Z", "amount": 100, "category": "Supermarket", "transaction_type": "expense", "spender_id": 1,
+			"splits": [{"category": "Groceries", "amount": 70}, {"category": "Household", "amount": 30, "spender_id": 2}]
+		}`)
+		var spent spentLog
+		err := New(cfg, db).WithAlerter(&spent).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"splits":[{"category":"Groceries","amount":70},{"category":"Household","amount":30,"spender_id":2}]`)
+		assert.Equal(t, spentLog{"1 Groceries 2024-04-30T09:00:00Z", "2 Household 2024-04-30T09:00:00Z"}, spent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("create transaction fail when the splits do not add up", func(t *testing.T) {
+		c, rec := newContext(`{
+			"date": "2024-04-30T09:00:00Z", "amount": 100, "category": "Supermarket", "transaction_type": "expense", "spender_id": 1,
+			"splits": [{"category": "Groceries", "amount": 70}, {"category": "Household", "amount": 20.5}]
+		}`)
+		err := New(cfg, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `{"field":"splits","message":"must add up to the amount 100.00, not 90.50"}`)
+	})
+
+	t.Run("create transaction fail when a split line is invalid", func(t *testing.T) {
+		c, rec := newContext(`{
+			"date": "2024-04-30T09:00:00Z", "amount": 100, "category": "Supermarket", "transaction_type": "expense", "spender_id": 1,
+			"splits": [{"category": "", "amount": 100}]
+		}`)
+		err := New(cfg, nil).Create(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"category"`)
+	})
+}
@@ -0,0 +1,262 @@
+package transaction
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/lib/pq"
+)
+
+// Statistics answers Stories 8 and 9 for the period of a summary, in the
+// home currency of the spender.
+type Statistics struct {
+	Days    int            `json:"days"`
+	Income  TypeStatistics `json:"income"`
+	Expense TypeStatistics `json:"expense"`
+}
+
+type TypeStatistics struct {
+	Total           money.Amount   `json:"total"`
+	Count           int            `json:"count"`
+	AveragePerDay   money.Amount   `json:"average_per_day"`
+	Median          money.Amount   `json:"median"`
+	Min             money.Amount   `json:"min"`
+	Max             money.Amount   `json:"max"`
+	LargestCategory *CategoryTotal `json:"largest_category"`
+}
+
+type CategoryTotal struct {
+	Category string       `json:"category"`
+	Total    money.Amount `json:"total"`
+}
+
+// UnknownType totals the transactions whose type is neither income nor
+// expense, they are left out of every other figure.
+type UnknownType struct {
+	TransactionType string       `json:"transaction_type"`
+	Count           int          `json:"count"`
+	Total           money.Amount `json:"total"`
+}
+
+// statRow aggregates the transactions of one type in one currency.
+type statRow struct {
+	SummaryTransaction
+	Count       int
+	Min, Max    money.Amount
+	First, Last *time.Time
+}
+
+const (
+	stats_stmt = `SELECT transaction_type as tran_type, currency, count(*), sum(amount), min(amount), max(amount), min(date), max(date) FROM transaction_line WHERE spender_id = $1 AND deleted_at IS NULL` + periodCond + ` group by transaction_type, currency`
+	// median_stmt converts every amount at the rates passed in $5 and $6
+	// before ranking it, and answers twice the median in minor units so the
+	// mean of the middle two stays a whole number
+	median_stmt = `SELECT transaction_type as tran_type, percentile_cont(0.5) WITHIN GROUP (ORDER BY round(amount * r.rate, 2) * 100) * 2 FROM transaction_line JOIN unnest($5::text[], $6::numeric[]) AS r(currency, rate) USING (currency) WHERE spender_id = $1 AND deleted_at IS NULL AND transaction_type IN ('income', 'expense')` + periodCond + ` group by transaction_type`
+)
+
+// getStatistics aggregates in the database, only a few rows for every type
+// and currency are converted here whatever the length of the period.
+func (h handler) getStatistics(ctx context.Context, cv *converter, id int, p Period) (Statistics, []UnknownType, error) {
+	srs, err := h.getStatRows(ctx, cv, id, p)
+	if err != nil {
+		return Statistics{}, nil, err
+	}
+
+	totals, err := h.getCategoryTotals(ctx, id, p)
+	if err != nil {
+		return Statistics{}, nil, err
+	}
+	for i, t := range totals {
+		if totals[i].SummaryTransaction, err = cv.convert(t.SummaryTransaction); err != nil {
+			return Statistics{}, nil, err
+		}
+	}
+
+	medians, err := h.getMedians(ctx, cv, id, p, srs)
+	if err != nil {
+		return Statistics{}, nil, err
+	}
+
+	stats, unknown := calculateStatistics(srs, totals, medians, p)
+	return stats, unknown, nil
+}
+
+// getStatRows reads the aggregates of every type and currency, with the
+// amounts converted into the home currency.
+func (h handler) getStatRows(ctx context.Context, cv *converter, id int, p Period) ([]statRow, error) {
+	rows, err := h.db.QueryContext(ctx, stats_stmt, append([]any{id}, p.args()...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var srs []statRow
+	for rows.Next() {
+		var r statRow
+		if err := rows.Scan(&r.TransactionType, &r.Currency, &r.Count, &r.TotalAmount, &r.Min, &r.Max, &r.First, &r.Last); err != nil {
+			return nil, err
+		}
+		for _, a := range []*money.Amount{&r.TotalAmount, &r.Min, &r.Max} {
+			if *a, err = cv.fx.Convert(ctx, *a, r.Currency); err != nil {
+				return nil, err
+			}
+		}
+		srs = append(srs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return srs, nil
+}
+
+// getMedians returns the median amount of income and of expense in the home
+// currency. It is the one figure that cannot be merged from the currencies
+// apart, so the database converts every amount first.
+func (h handler) getMedians(ctx context.Context, cv *converter, id int, p Period, srs []statRow) (map[string]money.Amount, error) {
+	var currencies, rates []string
+	for _, r := range srs {
+		if slices.Contains(currencies, r.Currency) {
+			continue
+		}
+		rate, err := cv.fx.Rate(ctx, r.Currency)
+		if err != nil {
+			return nil, err
+		}
+		currencies = append(currencies, r.Currency)
+		rates = append(rates, rate.FloatString(12))
+	}
+	if len(currencies) == 0 {
+		return nil, nil
+	}
+
+	args := append([]any{id}, p.args()...)
+	rows, err := h.db.QueryContext(ctx, median_stmt, append(args, pq.Array(currencies), pq.Array(rates))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	medians := map[string]money.Amount{}
+	for rows.Next() {
+		var tranType string
+		var twice float64
+		if err := rows.Scan(&tranType, &twice); err != nil {
+			return nil, err
+		}
+		medians[tranType] = money.FromMinor(int64(math.Round(twice))).DivRound(2)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return medians, nil
+}
+
+func calculateStatistics(srs []statRow, totals []categoryTotal, medians map[string]money.Amount, p Period) (Statistics, []UnknownType) {
+	byType := map[string][]statRow{}
+	unknown := map[string]*UnknownType{}
+	for _, r := range srs {
+		switch r.TransactionType {
+		case "income", "expense":
+			byType[r.TransactionType] = append(byType[r.TransactionType], r)
+		default:
+			u, ok := unknown[r.TransactionType]
+			if !ok {
+				u = &UnknownType{TransactionType: r.TransactionType}
+				unknown[r.TransactionType] = u
+			}
+			u.Count += r.Count
+			u.Total += r.TotalAmount
+		}
+	}
+
+	byCategory := map[string]map[string]money.Amount{}
+	for _, t := range totals {
+		if byCategory[t.TransactionType] == nil {
+			byCategory[t.TransactionType] = map[string]money.Amount{}
+		}
+		byCategory[t.TransactionType][t.Category] += t.TotalAmount
+	}
+
+	days := p.days(srs)
+	stats := Statistics{
+		Days:    days,
+		Income:  typeStatistics(byType["income"], byCategory["income"], medians["income"], days),
+		Expense: typeStatistics(byType["expense"], byCategory["expense"], medians["expense"], days),
+	}
+
+	var unknownTypes []UnknownType
+	for _, u := range unknown {
+		unknownTypes = append(unknownTypes, *u)
+	}
+	sort.Slice(unknownTypes, func(i, j int) bool {
+		return unknownTypes[i].TransactionType < unknownTypes[j].TransactionType
+	})
+	return stats, unknownTypes
+}
+
+func typeStatistics(srs []statRow, byCategory map[string]money.Amount, median money.Amount, days int) TypeStatistics {
+	if len(srs) == 0 {
+		return TypeStatistics{}
+	}
+
+	s := TypeStatistics{Median: median, Min: srs[0].Min, Max: srs[0].Max}
+	for _, r := range srs {
+		s.Total += r.TotalAmount
+		s.Count += r.Count
+		s.Min = min(s.Min, r.Min)
+		s.Max = max(s.Max, r.Max)
+	}
+	s.AveragePerDay = s.Total.DivRound(int64(days))
+
+	for category, total := range byCategory {
+		largest := s.LargestCategory
+		if largest == nil || total > largest.Total || (total == largest.Total && category < largest.Category) {
+			s.LargestCategory = &CategoryTotal{Category: category, Total: total}
+		}
+	}
+	return s
+}
+
+// days counts the calendar days of the period in the spender's timezone.
+// An open end is closed by the first or last transaction.
+func (p Period) days(srs []statRow) int {
+	var first, last *time.Time
+	for _, r := range srs {
+		if r.First != nil && (first == nil || r.First.Before(*first)) {
+			first = r.First
+		}
+		if r.Last != nil && (last == nil || r.Last.After(*last)) {
+			last = r.Last
+		}
+	}
+
+	var start, end time.Time
+	switch {
+	case p.From != nil:
+		start = *p.From
+	case first != nil:
+		start = p.day(*first)
+	default:
+		return 0
+	}
+	switch {
+	case p.To != nil:
+		end = *p.To
+	case last != nil:
+		end = p.day(*last).AddDate(0, 0, 1)
+	default:
+		return 0
+	}
+
+	// hours rather than a plain division keep a DST change from losing a day
+	return int(math.Round(end.Sub(start).Hours() / 24))
+}
+
+func (p Period) day(t time.Time) time.Time {
+	y, m, d := t.In(p.loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, p.loc)
+}
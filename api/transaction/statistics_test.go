@@ -0,0 +1,98 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateStatistics(t *testing.T) {
+	bkk, _ := time.LoadLocation("Asia/Bangkok")
+	at := func(d int) *time.Time {
+		t := time.Date(2024, 5, d, 12, 0, 0, 0, bkk)
+		return &t
+	}
+	row := func(tranType, currency string, count int, total, min, max string, first, last *time.Time) statRow {
+		return statRow{
+			SummaryTransaction: SummaryTransaction{TransactionType: tranType, TotalAmount: money.MustParse(total), Currency: currency},
+			Count:              count,
+			Min:                money.MustParse(min),
+			Max:                money.MustParse(max),
+			First:              first,
+			Last:               last,
+		}
+	}
+	category := func(tranType, name, total string) categoryTotal {
+		return categoryTotal{Category: name, SummaryTransaction: SummaryTransaction{TransactionType: tranType, TotalAmount: money.MustParse(total)}}
+	}
+
+	t.Run("figures of every currency are merged", func(t *testing.T) {
+		stats, unknown := calculateStatistics([]statRow{
+			row("expense", "THB", 3, "50.01", "0.01", "40", at(1), at(3)),
+			row("expense", "USD", 1, "25", "25", "25", at(2), at(2)),
+		}, []categoryTotal{
+			category("expense", "Food", "50"),
+			category("expense", "Travel", "25"),
+			category("expense", "Rent", "0.01"),
+		}, map[string]money.Amount{"expense": money.MustParse("17.5")}, Period{loc: bkk})
+
+		assert.Empty(t, unknown)
+		assert.Equal(t, 3, stats.Days)
+		assert.Equal(t, TypeStatistics{
+			Total:           money.MustParse("75.01"),
+			Count:           4,
+			AveragePerDay:   money.MustParse("25"),
+			Median:          money.MustParse("17.5"),
+			Min:             money.MustParse("0.01"),
+			Max:             money.MustParse("40"),
+			LargestCategory: &CategoryTotal{Category: "Food", Total: money.MustParse("50")},
+		}, stats.Expense)
+		assert.Equal(t, TypeStatistics{}, stats.Income)
+	})
+
+	t.Run("a category recorded in several currencies is one category", func(t *testing.T) {
+		stats, _ := calculateStatistics([]statRow{
+			row("expense", "THB", 2, "70", "30", "40", at(1), at(1)),
+		}, []categoryTotal{
+			category("expense", "Food", "30"),
+			category("expense", "Travel", "40"),
+			category("expense", "Food", "15"),
+		}, nil, Period{loc: bkk})
+
+		assert.Equal(t, &CategoryTotal{Category: "Food", Total: money.MustParse("45")}, stats.Expense.LargestCategory)
+	})
+
+	t.Run("the range of the period sets the number of days", func(t *testing.T) {
+		from := time.Date(2024, 5, 1, 0, 0, 0, 0, bkk)
+		to := time.Date(2024, 6, 1, 0, 0, 0, 0, bkk)
+
+		stats, _ := calculateStatistics([]statRow{row("income", "THB", 1, "31000", "31000", "31000", at(25), at(25))}, nil, nil, Period{From: &from, To: &to, loc: bkk})
+
+		assert.Equal(t, 31, stats.Days)
+		assert.Equal(t, money.MustParse("1000"), stats.Income.AveragePerDay)
+	})
+
+	t.Run("other transaction types are reported, not counted", func(t *testing.T) {
+		stats, unknown := calculateStatistics([]statRow{
+			row("income", "THB", 1, "100", "100", "100", at(1), at(1)),
+			row("transfer", "THB", 1, "30", "30", "30", at(1), at(1)),
+			row("Income", "THB", 1, "5", "5", "5", at(1), at(1)),
+			row("transfer", "USD", 1, "20", "20", "20", at(1), at(1)),
+		}, nil, nil, Period{loc: bkk})
+
+		assert.Equal(t, 1, stats.Income.Count)
+		assert.Equal(t, []UnknownType{
+			{TransactionType: "Income", Count: 1, Total: money.MustParse("5")},
+			{TransactionType: "transfer", Count: 2, Total: money.MustParse("50")},
+		}, unknown)
+	})
+
+	t.Run("no transactions", func(t *testing.T) {
+		stats, unknown := calculateStatistics(nil, nil, nil, Period{loc: bkk})
+
+		assert.Equal(t, Statistics{}, stats)
+		assert.Nil(t, unknown)
+	})
+}
@@ -0,0 +1,104 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	tagsStmt       = `SELECT g.name FROM transaction_tag tt JOIN tag g ON g.id = tt.tag_id WHERE tt.transaction_id = $1 ORDER BY g.name;`
+	listTagsStmt   = `SELECT tt.transaction_id, g.name FROM transaction_tag tt JOIN tag g ON g.id = tt.tag_id WHERE tt.transaction_id = ANY($1) ORDER BY g.name;`
+	insertTagStmt  = `WITH g AS (INSERT INTO tag (name) VALUES ($2) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING id) INSERT INTO transaction_tag (transaction_id, tag_id) SELECT $1, id FROM g ON CONFLICT DO NOTHING;`
+	deleteTagsStmt = `DELETE FROM transaction_tag WHERE transaction_id = $1;`
+)
+
+// normalizeTags lower cases and trims the tags, drops the duplicates that
+// leaves and sorts them, the way they are stored.
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// replaceTags swaps the tags of a transaction for tags.
+func replaceTags(ctx context.Context, tx *sql.Tx, id int64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, deleteTagsStmt, id); err != nil {
+		return err
+	}
+	return insertTags(ctx, tx, id, tags)
+}
+
+// insertTags tags a transaction, creating the tags not seen before.
+func insertTags(ctx context.Context, tx *sql.Tx, id int64, tags []string) error {
+	for _, t := range tags {
+		if _, err := tx.ExecContext(ctx, insertTagStmt, id, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getTags(ctx context.Context, q queryer, id int64) ([]string, error) {
+	rows, err := q.QueryContext(ctx, tagsStmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// fillTags loads the tags of a page of transactions in one query.
+func fillTags(ctx context.Context, q queryer, tRs []TransactionResponse) error {
+	if len(tRs) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(tRs))
+	byID := make(map[int64]*TransactionResponse, len(tRs))
+	for i := range tRs {
+		ids[i] = tRs[i].ID
+		byID[tRs[i].ID] = &tRs[i]
+	}
+
+	rows, err := q.QueryContext(ctx, listTagsStmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var t string
+		if err := rows.Scan(&id, &t); err != nil {
+			return err
+		}
+		if tR, ok := byID[id]; ok {
+			tR.Tags = append(tR.Tags, t)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,140 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"reimbursable", "trip-japan"}, normalizeTags([]string{" Trip-Japan", "reimbursable", "TRIP-JAPAN"}))
+	assert.Nil(t, normalizeTags(nil))
+}
+
+func TestTagFilter(t *testing.T) {
+	newContext := func(query string) echo.Context {
+		return echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/?"+query, nil), httptest.NewRecorder())
+	}
+
+	t.Run("any of the tags", func(t *testing.T) {
+		f, err := parseFilter(newContext("tag=Trip-Japan&tag=reimbursable"))
+		assert.NoError(t, err)
+
+		where, args := f.where()
+
+		assert.Equal(t, ` WHERE deleted_at IS NULL AND id IN (SELECT tt.transaction_id FROM transaction_tag tt JOIN tag g ON g.id = tt.tag_id WHERE g.name = ANY($1))`, where)
+		assert.Equal(t, []any{pq.Array([]string{"reimbursable", "trip-japan"})}, args)
+	})
+
+	t.Run("all of the tags", func(t *testing.T) {
+		f, err := parseFilter(newContext("tag=trip-japan&tag=reimbursable&tag_match=all"))
+		assert.NoError(t, err)
+
+		where, _ := f.where()
+
+		assert.Equal(t, ` WHERE deleted_at IS NULL AND id IN (SELECT tt.transaction_id FROM transaction_tag tt JOIN tag g ON g.id = tt.tag_id WHERE g.name = ANY($1) GROUP BY tt.transaction_id HAVING count(*) = 2)`, where)
+	})
+
+	t.Run("invalid match returns error", func(t *testing.T) {
+		_, err := parseFilter(newContext("tag=trip-japan&tag_match=some"))
+
+		assert.EqualError(t, err, "invalid query parameter: tag_match")
+	})
+}
+
+func TestCreateTaggedTransaction(t *testing.T) {
+	db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	defer db.Close()
+
+	date := time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(cStmt).WithArgs(date, money.MustParse("1200"), "Travel", "expense", "", "", int64(1), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(5, "THB"))
+	mock.ExpectExec(insertTagStmt).WithArgs(int64(5), "reimbursable").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insertTagStmt).WithArgs(int64(5), "trip-japan").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	e := echo.New()
+	e.Validator = validate.New(nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transactions", strings.NewReader(
+		`{"date": "2024-04-30T09:00:00Z", "amount": 1200, "category": "Travel", "transaction_type": "expense", "spender_id": 1, "tags": ["Trip-Japan", "reimbursable", "trip-japan"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	err := New(config.FeatureFlag{EnableCreateTransaction: true}, db).Create(e.NewContext(req, rec))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"tags":["reimbursable","trip-japan"]`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSpenderTagSummary(t *testing.T) {
+	db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	defer db.Close()
+
+	mock.ExpectQuery(spenderStmt).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"currency", "timezone"}).AddRow("THB", "Asia/Bangkok"))
+	mock.ExpectQuery(tags_stmt).WithArgs(1, nil, nil, true).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "tran_type", "currency", "total_amount", "total_count"}).
+			AddRow("trip-japan", "expense", "THB", "5000.00", 3).
+			AddRow("reimbursable", "expense", "THB", "1200.00", 1).
+			AddRow("reimbursable", "income", "THB", "1200.00", 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/spenders/1/transactions/summary/tags?exclude_transfers=true", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	err := handler{db: db}.GetSpenderTagSummary(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"currency": "THB",
+		"tags": [
+			{"tag": "reimbursable", "income": 1200, "expense": 1200, "count": 2},
+			{"tag": "trip-japan", "income": 0, "expense": 5000, "count": 3}
+		]
+	}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatchTags(t *testing.T) {
+	db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	defer db.Close()
+
+	date := time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE transaction SET version = version + 1 WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`).
+		WithArgs(1, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}).
+			AddRow(1, date, "100.00", "Food", "expense", "", "", 1, "THB", 2))
+	mock.ExpectQuery(splitsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"category", "amount", "spender_id"}))
+	mock.ExpectExec(deleteTagsStmt).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(insertTagStmt).WithArgs(int64(1), "work").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(mirrorStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"transaction_type", "spender_id"}))
+	mock.ExpectCommit()
+
+	e := echo.New()
+	e.Validator = validate.New(nil)
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"tags": ["Work"]}`))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	err := New(config.FeatureFlag{EnableUpdateTransaction: true}, db).Patch(c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"tags":["work"]`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
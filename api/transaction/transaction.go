@@ -1,23 +1,54 @@
 package transaction
 
-import "time"
+import (
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+)
 
 type TransactionRequest struct {
-	Date            time.Time `json:"date"`
-	Amount          float64   `json:"amount"`
-	Category        string    `json:"category"`
-	TransactionType string    `json:"transaction_type"`
-	Note            string    `json:"note"`
-	ImageUrl        string    `json:"image_url"`
-	SpenderID       int64     `json:"spender_id"`
+	Date            time.Time    `json:"date" validate:"required"`
+	Amount          money.Amount `json:"amount" validate:"gt=0,money"`
+	Category        string       `json:"category" validate:"required,max=50,nocontrol"`
+	TransactionType string       `json:"transaction_type" validate:"oneof=income expense"`
+	Note            string       `json:"note" validate:"max=255"`
+	ImageUrl        string       `json:"image_url" validate:"omitempty,url,max=255"`
+	SpenderID       int64        `json:"spender_id" validate:"required,exists=spender"`
+	// Currency defaults to the home currency of the spender when left empty.
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	// Splits share the amount out over categories and spenders, they must
+	// add up to Amount.
+	Splits []Split `json:"splits,omitempty" validate:"omitempty,dive"`
+	// Tags are free-form labels, stored in lower case.
+	Tags []string `json:"tags,omitempty" validate:"omitempty,dive,required,max=50,nocontrol"`
+}
+
+// Split is a share of a transaction booked on its own category, and on
+// another spender when SpenderID is set.
+type Split struct {
+	Category  string       `json:"category" validate:"required,max=50,nocontrol"`
+	Amount    money.Amount `json:"amount" validate:"gt=0,money"`
+	SpenderID *int64       `json:"spender_id,omitempty" validate:"omitempty,exists=spender"`
 }
 
 type TransactionResponse struct {
-	ID              int64      `json:"id"`
-	Date            *time.Time `json:"date"`
-	Amount          float64    `json:"amount"`
-	Category        string     `json:"category"`
-	TransactionType string     `json:"transaction_type"`
-	Note            string     `json:"note"`
-	ImageUrl        string     `json:"image_url"`
+	ID              int64        `json:"id"`
+	Date            *time.Time   `json:"date"`
+	Amount          money.Amount `json:"amount"`
+	Category        string       `json:"category"`
+	TransactionType string       `json:"transaction_type"`
+	Note            string       `json:"note"`
+	ImageUrl        string       `json:"image_url"`
+	SpenderID       *int64       `json:"spender_id"`
+	Currency        string       `json:"currency"`
+	// RunningBalance is the balance in Currency after this transaction, only
+	// set when a listing asks for it.
+	RunningBalance *money.Amount `json:"running_balance,omitempty"`
+	Splits         []Split       `json:"splits,omitempty"`
+	Tags           []string      `json:"tags,omitempty"`
+}
+
+// fields returns the scan destinations in the order of selectColumns.
+func (tR *TransactionResponse) fields() []any {
+	return []any{&tR.ID, &tR.Date, &tR.Amount, &tR.Category, &tR.TransactionType, &tR.Note, &tR.ImageUrl, &tR.SpenderID, &tR.Currency}
 }
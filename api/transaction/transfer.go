@@ -0,0 +1,143 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// TransferCategory is the category of both sides of a transfer.
+const TransferCategory = "Transfer"
+
+// TransferRequest moves Amount from one spender to another.
+type TransferRequest struct {
+	FromSpenderID int64        `json:"from_spender_id" validate:"required,exists=spender"`
+	ToSpenderID   int64        `json:"to_spender_id" validate:"required,nefield=FromSpenderID,exists=spender"`
+	Date          time.Time    `json:"date" validate:"required"`
+	Amount        money.Amount `json:"amount" validate:"gt=0,money"`
+	// Currency defaults to the home currency of the sender when left empty.
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	Note     string `json:"note" validate:"max=255"`
+}
+
+// TransferResponse is a transfer with the expense booked on the sender and
+// the income booked on the receiver.
+type TransferResponse struct {
+	ID      int64               `json:"id"`
+	Expense TransactionResponse `json:"expense"`
+	Income  TransactionResponse `json:"income"`
+}
+
+const (
+	transferStmt     = `INSERT INTO transfer DEFAULT VALUES RETURNING id;`
+	transferSideStmt = `INSERT INTO transaction (date, amount, category, transaction_type, note, image_url, spender_id, currency, transfer_id) VALUES ($1, $2, $3, $4, $5, $6, $7, ` + currencyExpr + `, $9) RETURNING id, currency;`
+	mirrorStmt       = `UPDATE transaction p SET date = t.date, amount = t.amount, currency = t.currency, note = t.note, version = p.version + 1 FROM transaction t WHERE t.id = $1 AND p.transfer_id = t.transfer_id AND p.id <> t.id AND p.deleted_at IS NULL RETURNING p.transaction_type, p.spender_id;`
+)
+
+// CreateTransfer writes both sides of a transfer in one database
+// transaction, so there is never an expense without its income.
+func (h handler) CreateTransfer(c echo.Context) error {
+	if !h.flag.EnableTransfer {
+		return c.JSON(http.StatusForbidden, "transfer feature is disabled")
+	}
+
+	logger := mlog.L(c)
+	ctx := c.Request().Context()
+
+	var req TransferRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Err{Message: "Invalid transfer request"})
+	}
+	req.Currency = strings.ToUpper(req.Currency)
+	if err := validate.Request(c, &req); err != nil {
+		return validate.Fail(c, err)
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("begin transaction error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	defer tx.Rollback()
+
+	var resp TransferResponse
+	if err := tx.QueryRowContext(ctx, transferStmt).Scan(&resp.ID); err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	// the income takes the currency resolved for the expense, so both sides
+	// move the same money
+	resp.Expense, err = insertTransferSide(ctx, tx, resp.ID, req, "expense", req.FromSpenderID, req.Currency)
+	if err == nil {
+		resp.Income, err = insertTransferSide(ctx, tx, resp.ID, req, "income", req.ToSpenderID, resp.Expense.Currency)
+	}
+	if err != nil {
+		logger.Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("commit error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Info("transfer successfully", zap.Int64("id", resp.ID))
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func insertTransferSide(ctx context.Context, tx *sql.Tx, transferID int64, req TransferRequest, tranType string, spenderID int64, currency string) (TransactionResponse, error) {
+	tR := TransactionResponse{
+		Date:            &req.Date,
+		Amount:          req.Amount,
+		Category:        TransferCategory,
+		TransactionType: tranType,
+		Note:            req.Note,
+		SpenderID:       &spenderID,
+	}
+	err := tx.QueryRowContext(ctx, transferSideStmt,
+		req.Date, req.Amount, TransferCategory, tranType, req.Note, "", spenderID, currency, transferID,
+	).Scan(&tR.ID, &tR.Currency)
+	return tR, err
+}
+
+// mirror copies the date, amount, currency and note of a transaction that
+// was just changed onto the other side of its transfer. It does nothing
+// for a transaction that is not a transfer. The two sides must stay an
+// expense and an income of two different spenders.
+func mirror(ctx context.Context, tx *sql.Tx, tR TransactionResponse) error {
+	var tranType string
+	var spenderID *int64
+	err := tx.QueryRowContext(ctx, mirrorStmt, tR.ID).Scan(&tranType, &spenderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if tranType == tR.TransactionType {
+		return validate.Errors{{Field: "transaction_type", Message: "must differ from the other side of the transfer"}}
+	}
+	if spenderID != nil && tR.SpenderID != nil && *spenderID == *tR.SpenderID {
+		return validate.Errors{{Field: "spender_id", Message: "must differ from the other side of the transfer"}}
+	}
+	return nil
+}
+
+// failMirror answers a request whose change could not be mirrored onto the
+// other side of the transfer.
+func failMirror(c echo.Context, err error) error {
+	if errors.As(err, &validate.Errors{}) {
+		return validate.Fail(c, err)
+	}
+	mlog.L(c).Error("mirror transfer error", zap.Error(err))
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,148 @@
+package transaction
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		return e.NewContext(req, rec), rec
+	}
+	cfg := config.FeatureFlag{EnableTransfer: true}
+
+	t.Run("create both sides of a transfer in one go", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		date := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(transferStmt).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(transferSideStmt).WithArgs(date, money.MustParse("500"), TransferCategory, "expense", "pocket money", "", int64(1), "", int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(10, "THB"))
+		mock.ExpectQuery(transferSideStmt).WithArgs(date, money.MustParse("500"), TransferCategory, "income", "pocket money", "", int64(2), "THB", int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(11, "THB"))
+		mock.ExpectCommit()
+
+		c, rec := newContext(`{"from_spender_id": 1, "to_spender_id": 2, "date": "2024-05-01T09:00:00Z", "amount": 500, "note": "pocket money"}`)
+		err := New(cfg, db).CreateTransfer(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{
+			"id": 3,
+			"expense": {"id": 10, "date": "2024-05-01T09:00:00Z", "amount": 500, "category": "Transfer", "transaction_type": "expense", "note": "pocket money", "image_url": "", "spender_id": 1, "currency": "THB"},
+			"income": {"id": 11, "date": "2024-05-01T09:00:00Z", "amount": 500, "category": "Transfer", "transaction_type": "income", "note": "pocket money", "image_url": "", "spender_id": 2, "currency": "THB"}
+		}`, rec.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("create transfer roll back when the income cannot be written", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(transferStmt).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(transferSideStmt).WillReturnRows(sqlmock.NewRows([]string{"id", "currency"}).AddRow(10, "THB"))
+		mock.ExpectQuery(transferSideStmt).WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		c, rec := newContext(`{"from_spender_id": 1, "to_spender_id": 2, "date": "2024-05-01T09:00:00Z", "amount": 500}`)
+		err := New(cfg, db).CreateTransfer(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("create transfer fail when both sides are the same spender", func(t *testing.T) {
+		c, rec := newContext(`{"from_spender_id": 1, "to_spender_id": 1, "date": "2024-05-01T09:00:00Z", "amount": 500}`)
+		err := New(cfg, nil).CreateTransfer(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"to_spender_id"`)
+	})
+
+	t.Run("create transfer fail when feature toggle is disable", func(t *testing.T) {
+		c, rec := newContext(`{}`)
+		err := New(config.FeatureFlag{}, nil).CreateTransfer(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestPatchTransferSide(t *testing.T) {
+	columns := []string{"id", "date", "amount", "category", "transaction_type", "note", "image_url", "spender_id", "currency", "version"}
+	patchStmt := `UPDATE transaction SET amount = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND ($3::int IS NULL OR version = $3) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		e := echo.New()
+		e.Validator = validate.New(nil)
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("10")
+		return c, rec
+	}
+	cfg := config.FeatureFlag{EnableUpdateTransaction: true}
+	date := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("patch one side of a transfer changes the other side too", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(patchStmt).WithArgs(money.MustParse("600"), 10, nil).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(10, date, "600.00", "Transfer", "expense", "", "", 1, "THB", 2))
+		mock.ExpectQuery(splitsStmt).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"category", "amount", "spender_id"}))
+		mock.ExpectQuery(tagsStmt).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery(mirrorStmt).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"transaction_type", "spender_id"}).AddRow("income", 2))
+		mock.ExpectCommit()
+
+		c, rec := newContext(`{"amount": 600}`)
+		err := New(cfg, db).Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("patch transfer side fail when both sides would be of one type", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE transaction SET transaction_type = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND ($3::int IS NULL OR version = $3) RETURNING id, date, amount, category, transaction_type, note, image_url, spender_id, currency, version;`).
+			WithArgs("income", 10, nil).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(10, date, "500.00", "Transfer", "income", "", "", 1, "THB", 2))
+		mock.ExpectQuery(splitsStmt).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"category", "amount", "spender_id"}))
+		mock.ExpectQuery(tagsStmt).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		mock.ExpectQuery(mirrorStmt).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"transaction_type", "spender_id"}).AddRow("income", 2))
+		mock.ExpectRollback()
+
+		c, rec := newContext(`{"transaction_type": "income"}`)
+		err := New(cfg, db).Patch(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Contains(t, rec.Body.String(), `{"field":"transaction_type","message":"must differ from the other side of the transfer"}`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,34 @@
+package transaction
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/etag"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/mlog"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const versionStmt = `SELECT version FROM transaction WHERE id = $1 AND deleted_at IS NULL;`
+
+// missed answers a conditional write that touched no row, either the
+// transaction does not exist or somebody else changed it first.
+func (h handler) missed(c echo.Context, id int, ifMatch *int64) error {
+	if ifMatch == nil {
+		return c.JSON(http.StatusNotFound, Err{Message: "transaction not found"})
+	}
+
+	var current int64
+	err := h.db.QueryRowContext(c.Request().Context(), versionStmt, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, Err{Message: "transaction not found"})
+	}
+	if err != nil {
+		mlog.L(c).Error("query row error", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return etag.PreconditionFailed(c, current)
+}
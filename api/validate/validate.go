@@ -0,0 +1,207 @@
+package validate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors lists every field of a request that broke one of its rules.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(msgs, ", ")
+}
+
+type Response struct {
+	Message string `json:"message"`
+	Errors  Errors `json:"errors"`
+}
+
+type dbErrKey struct{}
+
+// Validator checks the `validate` struct tags of a request. Besides the
+// built-in rules it knows `exists=<table>`, which looks the id up in the
+// given table, and `money`, which caps an amount at what the columns hold.
+type Validator struct {
+	v  *validator.Validate
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Validator {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	cv := &Validator{v: v, db: db}
+	_ = v.RegisterValidationCtx("exists", cv.exists)
+	_ = v.RegisterValidation("nocontrol", noControl)
+	_ = v.RegisterValidation("money", fitsColumn)
+	return cv
+}
+
+// Validate implements echo.Validator.
+func (cv *Validator) Validate(i any) error {
+	return cv.ValidateCtx(context.Background(), i)
+}
+
+// ValidateCtx is Validate with the database lookups bound to ctx, so they
+// stop when the request is cancelled.
+func (cv *Validator) ValidateCtx(ctx context.Context, i any) error {
+	return cv.run(ctx, func(ctx context.Context) error {
+		return cv.v.StructCtx(ctx, i)
+	})
+}
+
+// ValidatePartial only checks the named struct fields, for requests such as
+// a merge patch that carry a subset of the fields.
+func (cv *Validator) ValidatePartial(i any, fields ...string) error {
+	return cv.ValidatePartialCtx(context.Background(), i, fields...)
+}
+
+// ValidatePartialCtx is ValidatePartial with the lookups bound to ctx.
+func (cv *Validator) ValidatePartialCtx(ctx context.Context, i any, fields ...string) error {
+	return cv.run(ctx, func(ctx context.Context) error {
+		return cv.v.StructPartialCtx(ctx, i, fields...)
+	})
+}
+
+func (cv *Validator) run(ctx context.Context, validate func(ctx context.Context) error) error {
+	// a rule cannot return an error, so a failing database lookup is parked
+	// here and reported instead of a misleading "does not exist"
+	var dbErr error
+	ctx = context.WithValue(ctx, dbErrKey{}, &dbErr)
+
+	err := validate(ctx)
+	if dbErr != nil {
+		return dbErr
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	errs := make(Errors, len(verrs))
+	for i, fe := range verrs {
+		errs[i] = FieldError{Field: fe.Field(), Message: message(fe)}
+	}
+	return errs
+}
+
+func (cv *Validator) exists(ctx context.Context, fl validator.FieldLevel) bool {
+	if cv.db == nil {
+		return true
+	}
+
+	var found bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1);", fl.Param())
+	err := cv.db.QueryRowContext(ctx, query, fl.Field().Interface()).Scan(&found)
+	if err != nil {
+		*ctx.Value(dbErrKey{}).(*error) = err
+		return false
+	}
+	return found
+}
+
+// noControl rejects control characters such as line breaks, which would let
+// a value that ends up in a mail header add headers of its own.
+func noControl(fl validator.FieldLevel) bool {
+	return !strings.ContainsFunc(fl.Field().String(), unicode.IsControl)
+}
+
+// fitsColumn rejects an amount too large for the DECIMAL(10,2) columns,
+// which the database would refuse with an error instead of a 422.
+func fitsColumn(fl validator.FieldLevel) bool {
+	return fl.Field().Int() <= int64(money.Max)
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param() + " characters"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "iso4217":
+		return "must be an ISO 4217 currency code"
+	case "timezone":
+		return "must be an IANA time zone such as Asia/Bangkok"
+	case "datetime":
+		return "must be formatted as " + fe.Param()
+	case "nefield":
+		return "must differ from " + fe.Param()
+	case "exists":
+		return "does not exist"
+	case "nocontrol":
+		return "must not contain control characters"
+	case "money":
+		return "must be at most " + money.Max.String()
+	default:
+		return "failed on the '" + fe.Tag() + "' rule"
+	}
+}
+
+// Request validates i with the echo validator, within the context of the
+// request when the validator takes one.
+func Request(c echo.Context, i any) error {
+	if cv, ok := c.Echo().Validator.(interface {
+		ValidateCtx(ctx context.Context, i any) error
+	}); ok {
+		return cv.ValidateCtx(c.Request().Context(), i)
+	}
+	return c.Validate(i)
+}
+
+// Partial validates the named fields with the echo validator when it
+// supports partial validation, and the whole struct otherwise.
+func Partial(c echo.Context, i any, fields ...string) error {
+	if pv, ok := c.Echo().Validator.(interface {
+		ValidatePartialCtx(ctx context.Context, i any, fields ...string) error
+	}); ok {
+		return pv.ValidatePartialCtx(c.Request().Context(), i, fields...)
+	}
+	return c.Validate(i)
+}
+
+// Fail writes the response for an error returned by the validator, a 422
+// listing the failing fields, or a 500 when the check itself broke.
+func Fail(c echo.Context, err error) error {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return c.JSON(http.StatusUnprocessableEntity, Response{Message: "validation failed", Errors: errs})
+	}
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
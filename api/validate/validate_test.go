@@ -0,0 +1,127 @@
+package validate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	Amount    float64 `json:"amount" validate:"gt=0"`
+	Type      string  `json:"transaction_type" validate:"oneof=income expense"`
+	Email     string  `json:"email" validate:"omitempty,email"`
+	Currency  string  `json:"currency" validate:"omitempty,iso4217"`
+	SpenderID int64   `json:"spender_id" validate:"required,exists=spender"`
+}
+
+const existsStmt = `SELECT EXISTS(SELECT 1 FROM spender WHERE id = $1);`
+
+func TestValidate(t *testing.T) {
+	t.Run("valid request", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(existsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+		err := New(db).Validate(&request{Amount: 10, Type: "income", SpenderID: 1})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("every failing field is listed by its json name", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(existsStmt).WithArgs(int64(9)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := New(db).Validate(&request{Amount: -1, Type: "banana", Email: "nope", Currency: "BAHT", SpenderID: 9})
+
+		assert.Equal(t, Errors{
+			{Field: "amount", Message: "must be greater than 0"},
+			{Field: "transaction_type", Message: "must be one of: income, expense"},
+			{Field: "email", Message: "must be a valid email address"},
+			{Field: "currency", Message: "must be an ISO 4217 currency code"},
+			{Field: "spender_id", Message: "does not exist"},
+		}, err)
+	})
+
+	t.Run("database failure is not reported as a missing row", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(existsStmt).WillReturnError(assert.AnError)
+
+		err := New(db).Validate(&request{Amount: 10, Type: "income", SpenderID: 1})
+
+		assert.Equal(t, assert.AnError, err)
+	})
+
+	t.Run("lookups stop with the request context", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectQuery(existsStmt).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := New(db).ValidateCtx(ctx, &request{Amount: 10, Type: "income", SpenderID: 1})
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("control characters are rejected", func(t *testing.T) {
+		err := New(nil).Validate(&struct {
+			Category string `json:"category" validate:"nocontrol"`
+		}{Category: "Food\r\nBcc: x@evil"})
+
+		assert.Equal(t, Errors{{Field: "category", Message: "must not contain control characters"}}, err)
+		assert.NoError(t, New(nil).Validate(&struct {
+			Category string `validate:"nocontrol"`
+		}{Category: "อาหาร"}))
+	})
+
+	t.Run("amounts beyond the amount columns are rejected", func(t *testing.T) {
+		type amount struct {
+			Amount money.Amount `json:"amount" validate:"money"`
+		}
+
+		err := New(nil).Validate(&amount{Amount: money.MustParse("100000000")})
+
+		assert.Equal(t, Errors{{Field: "amount", Message: "must be at most 99999999.99"}}, err)
+		assert.NoError(t, New(nil).Validate(&amount{Amount: money.Max}))
+	})
+
+	t.Run("partial validation only checks the named fields", func(t *testing.T) {
+		err := New(nil).ValidatePartial(&request{Amount: 5}, "Amount")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestFail(t *testing.T) {
+	t.Run("validation errors are unprocessable entity", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+
+		err := Fail(c, Errors{{Field: "amount", Message: "must be greater than 0"}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"message":"validation failed","errors":[{"field":"amount","message":"must be greater than 0"}]}`, rec.Body.String())
+	})
+
+	t.Run("other errors are internal server error", func(t *testing.T) {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+
+		err := Fail(c, assert.AnError)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
@@ -3,28 +3,100 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
+	_ "time/tzdata" // spender timezones must load on images without zoneinfo
 
 	"github.com/KKGo-Software-engineering/workshop-summer/api"
 	"github.com/KKGo-Software-engineering/workshop-summer/api/config"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/idempotency"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/notify"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/recurring"
 	"github.com/KKGo-Software-engineering/workshop-summer/migration"
+	"github.com/KKGo-Software-engineering/workshop-summer/seed"
 	"github.com/labstack/gommon/log"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
+const usage = `usage: %s [-env PREFIX] [COMMAND]
+
+commands:
+  serve          run the server, the default
+  migrate        manage the database schema, run "migrate" alone for its commands
+  seed FILE      load spenders and their transactions from a .yaml or .json file
+  check-config   print the config with secrets redacted, fail when it is invalid
+
+flags:
+`
+
 func main() {
-	env := config.Env("ENV")
-	cfg := config.Parse(env)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0])
+		flag.PrintDefaults()
+	}
+	env := flag.String("env", os.Getenv("ENV"), "prefix of the environment variables, ENV when not given")
+	flag.Parse()
+
+	command, args := "serve", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		if len(args) != 0 {
+			err = errors.New("serve takes no arguments")
+			break
+		}
+		serve(config.Parse(*env))
+	case "migrate":
+		err = migrate(*env, args)
+	case "seed":
+		if len(args) != 1 {
+			err = errors.New("seed needs a FILE")
+			break
+		}
+		err = seedFile(config.Parse(*env), args[0])
+	case "check-config":
+		err = checkConfig(*env)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
 
+func openDB(cfg config.Config) *sql.DB {
 	db, err := sql.Open("postgres", cfg.Database.PostgresURI)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := migration.ApplyMigrations(db); err != nil {
+	return db
+}
+
+func serve(cfg config.Config) {
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	db := openDB(cfg)
+
+	var err error
+	if cfg.Database.AutoMigrate {
+		err = migration.ApplyMigrations(db)
+	} else {
+		err = migration.EnsureCurrent(context.Background(), db)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 
@@ -33,7 +105,10 @@ func main() {
 		log.Fatal(err)
 	}
 
-	e := api.New(db, cfg, logger)
+	e, err := api.New(db, cfg, logger)
+	if err != nil {
+		logger.Fatal("notifier config error", zap.Error(err))
+	}
 
 	go func() { // comment here to simulate slow endpoint then Ctrl+C to stop the server
 		if err := e.Start(":" + cfg.Server.Port); err != nil && err != http.ErrServerClosed {
@@ -43,6 +118,18 @@ func main() {
 
 	logger.Info("Server is running on :%s", zap.String("port", cfg.Server.Port))
 
+	schedCtx, stopScheduler := context.WithCancel(context.Background())
+	var sched sync.WaitGroup
+	sched.Add(2)
+	go func() {
+		defer sched.Done()
+		recurring.NewScheduler(db, cfg.Scheduler.Interval, logger).Run(schedCtx)
+	}()
+	go func() {
+		defer sched.Done()
+		idempotency.RunPurge(schedCtx, db, cfg.Scheduler.Interval, logger)
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
 	sig, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
@@ -54,5 +141,54 @@ func main() {
 	if err := e.Shutdown(ctx); err != nil {
 		logger.Fatal("shutting down the server:", zap.Error(err))
 	}
+	stopScheduler()
+	sched.Wait()
 	logger.Info("server shutdown gracefully")
 }
+
+// migrate runs a migrate command, connecting to the database only for the
+// commands that use it so create and validate work without one.
+func migrate(env string, args []string) error {
+	var db *sql.DB
+	if migration.NeedsDB(args) {
+		db = openDB(config.Parse(env))
+		defer db.Close()
+	}
+	return migration.Run(context.Background(), db, os.Stdout, args)
+}
+
+// seedFile loads fixtures into a database that has to be migrated already.
+func seedFile(cfg config.Config, path string) error {
+	f, err := seed.Load(path)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db := openDB(cfg)
+	if err := migration.EnsureCurrent(ctx, db); err != nil {
+		return err
+	}
+	r, err := seed.Apply(ctx, db, f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("seeded %d spenders with %d transactions, skipped %d spenders that already exist\n", r.Spenders, r.Transactions, r.Skipped)
+	return nil
+}
+
+// checkConfig prints the config the server would run with, then fails
+// when it could not be parsed or would not work.
+func checkConfig(env string) error {
+	cfg, err := config.Load(env)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Redacted().WriteEnv(os.Stdout); err != nil {
+		return err
+	}
+
+	_, err = notify.New(cfg.Notify, zap.NewNop())
+	return errors.Join(cfg.Validate(), err)
+}
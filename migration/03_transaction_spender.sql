@@ -1,10 +1,9 @@
 -- +goose Up
 -- +goose StatementBegin
-ALTER TABLE  "transaction" (
-  ADD spender_id INT DEFAULT NULL
-);
+ALTER TABLE "transaction" ADD COLUMN IF NOT EXISTS spender_id INT DEFAULT NULL;
 -- +goose StatementEnd
 
 -- +goose Down
 -- +goose StatementBegin
+ALTER TABLE "transaction" DROP COLUMN IF EXISTS spender_id;
 -- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "idempotency_key" (
+  key VARCHAR(255) PRIMARY KEY,
+  request_hash CHAR(64) NOT NULL,
+  status INT DEFAULT NULL,
+  content_type VARCHAR(255) DEFAULT NULL,
+  body BYTEA DEFAULT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "idempotency_key";
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "spender" ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'THB';
+ALTER TABLE "transaction" ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'THB';
+
+CREATE TABLE IF NOT EXISTS "fx_rate" (
+  base CHAR(3) NOT NULL,
+  quote CHAR(3) NOT NULL,
+  rate NUMERIC(18,8) NOT NULL CHECK (rate > 0),
+  effective_date DATE NOT NULL,
+  PRIMARY KEY (base, quote, effective_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "fx_rate";
+ALTER TABLE "transaction" DROP COLUMN IF EXISTS currency;
+ALTER TABLE "spender" DROP COLUMN IF EXISTS currency;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "budget" (
+  id SERIAL PRIMARY KEY,
+  spender_id INT NOT NULL REFERENCES spender(id) ON DELETE CASCADE,
+  category VARCHAR(50) NOT NULL,
+  period VARCHAR(10) NOT NULL CHECK (period IN ('monthly', 'weekly')),
+  amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+  UNIQUE (spender_id, category, period)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "budget";
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "budget_alert" (
+  budget_id INT NOT NULL REFERENCES budget(id) ON DELETE CASCADE,
+  threshold INT NOT NULL,
+  period_start DATE NOT NULL,
+  sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (budget_id, threshold, period_start)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "budget_alert";
+-- +goose StatementEnd
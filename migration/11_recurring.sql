@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "recurring" (
+  id SERIAL PRIMARY KEY,
+  spender_id INT NOT NULL REFERENCES spender(id) ON DELETE CASCADE,
+  amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+  category VARCHAR(50) NOT NULL,
+  transaction_type VARCHAR(20) NOT NULL,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  currency CHAR(3) NOT NULL,
+  rule VARCHAR(255) NOT NULL,
+  starts_on DATE NOT NULL,
+  until DATE,
+  next_run DATE
+);
+CREATE INDEX IF NOT EXISTS recurring_next_run_idx ON "recurring" (next_run);
+
+ALTER TABLE "transaction" ADD COLUMN IF NOT EXISTS recurring_id INT REFERENCES recurring(id) ON DELETE SET NULL;
+ALTER TABLE "transaction" ADD COLUMN IF NOT EXISTS occurrence DATE;
+CREATE UNIQUE INDEX IF NOT EXISTS transaction_recurring_occurrence_idx ON "transaction" (recurring_id, occurrence);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transaction_recurring_occurrence_idx;
+ALTER TABLE "transaction" DROP COLUMN IF EXISTS occurrence;
+ALTER TABLE "transaction" DROP COLUMN IF EXISTS recurring_id;
+DROP TABLE IF EXISTS "recurring";
+-- +goose StatementEnd
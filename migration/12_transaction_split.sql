@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "transaction_split" (
+  id SERIAL PRIMARY KEY,
+  transaction_id INT NOT NULL REFERENCES transaction(id) ON DELETE CASCADE,
+  category VARCHAR(50) NOT NULL,
+  amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+  spender_id INT REFERENCES spender(id)
+);
+CREATE INDEX IF NOT EXISTS transaction_split_transaction_id_idx ON "transaction_split" (transaction_id);
+
+-- transaction_line has one row per split line, and the transaction itself
+-- when it is not split, so reports count every share where it belongs
+CREATE OR REPLACE VIEW "transaction_line" AS
+SELECT t.id, t.date, t.transaction_type, t.currency, t.deleted_at,
+       COALESCE(s.spender_id, t.spender_id) AS spender_id,
+       COALESCE(s.category, t.category) AS category,
+       COALESCE(s.amount, t.amount) AS amount
+FROM "transaction" t
+LEFT JOIN "transaction_split" s ON s.transaction_id = t.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW IF EXISTS "transaction_line";
+DROP TABLE IF EXISTS "transaction_split";
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS "transfer" (
+  id SERIAL PRIMARY KEY,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- both sides of a transfer, an expense and an income, share its id
+ALTER TABLE "transaction" ADD COLUMN IF NOT EXISTS transfer_id INT REFERENCES transfer(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS transaction_transfer_id_idx ON "transaction" (transfer_id) WHERE transfer_id IS NOT NULL;
+
+CREATE OR REPLACE VIEW "transaction_line" AS
+SELECT t.id, t.date, t.transaction_type, t.currency, t.deleted_at,
+       COALESCE(s.spender_id, t.spender_id) AS spender_id,
+       COALESCE(s.category, t.category) AS category,
+       COALESCE(s.amount, t.amount) AS amount,
+       t.transfer_id
+FROM "transaction" t
+LEFT JOIN "transaction_split" s ON s.transaction_id = t.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- a view cannot lose a column with CREATE OR REPLACE
+DROP VIEW IF EXISTS "transaction_line";
+CREATE VIEW "transaction_line" AS
+SELECT t.id, t.date, t.transaction_type, t.currency, t.deleted_at,
+       COALESCE(s.spender_id, t.spender_id) AS spender_id,
+       COALESCE(s.category, t.category) AS category,
+       COALESCE(s.amount, t.amount) AS amount
+FROM "transaction" t
+LEFT JOIN "transaction_split" s ON s.transaction_id = t.id;
+
+DROP INDEX IF EXISTS transaction_transfer_id_idx;
+ALTER TABLE "transaction" DROP COLUMN IF EXISTS transfer_id;
+DROP TABLE IF EXISTS "transfer";
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- names are stored in lower case, so "Trip-Japan" and "trip-japan" are one tag
+CREATE TABLE IF NOT EXISTS "tag" (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(50) NOT NULL UNIQUE CHECK (name = lower(name))
+);
+
+CREATE TABLE IF NOT EXISTS "transaction_tag" (
+  transaction_id INT NOT NULL REFERENCES transaction(id) ON DELETE CASCADE,
+  tag_id INT NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+  PRIMARY KEY (transaction_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS transaction_tag_tag_id_idx ON "transaction_tag" (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS "transaction_tag";
+DROP TABLE IF EXISTS "tag";
+-- +goose StatementEnd
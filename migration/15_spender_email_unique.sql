@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- emails are compared without case. Of the spenders sharing an email the one
+-- with the lowest id keeps it, the others are flagged with
+-- "#duplicate-of-<id>" so the index can be built; find them with
+-- email LIKE '%#duplicate-of-%' and merge them by hand
+UPDATE "spender" SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+UPDATE "spender" s SET email = s.email || '#duplicate-of-' || k.id
+  FROM (SELECT email, min(id) AS id FROM "spender" GROUP BY email) k
+  WHERE s.email = k.email AND s.id <> k.id;
+CREATE UNIQUE INDEX IF NOT EXISTS spender_email_key ON "spender" (lower(email));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS spender_email_key;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- transactions of a spender that no longer exists cannot be shown anywhere,
+-- they lose the spender rather than block the constraint
+UPDATE "transaction" SET spender_id = NULL
+WHERE spender_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM "spender" s WHERE s.id = spender_id);
+
+-- deleting a spender decides what happens to its transactions first, see
+-- the transactions parameter of DELETE /spenders/:id, so anything left
+-- behind is a mistake the database refuses
+ALTER TABLE "transaction" ADD CONSTRAINT transaction_spender_id_fkey
+  FOREIGN KEY (spender_id) REFERENCES spender(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "transaction" DROP CONSTRAINT IF EXISTS transaction_spender_id_fkey;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a transaction without a date cannot be placed in any period and is left
+-- out of the running balance and the series; legacy rows keep their NULL,
+-- every new or changed row needs a date. Give the old rows one by hand, find
+-- them with date IS NULL, then VALIDATE CONSTRAINT
+ALTER TABLE "transaction" ADD CONSTRAINT transaction_date_check
+  CHECK (date IS NOT NULL) NOT VALID;
+
+-- a missing amount already added nothing to any total, 0 keeps it that way
+UPDATE "transaction" SET amount = 0 WHERE amount IS NULL;
+ALTER TABLE "transaction" ALTER COLUMN amount SET NOT NULL;
+
+-- legacy rows may carry '' or any other type, the summary reports them as
+-- unknown types; the check holds for every new or changed row and is
+-- validated with VALIDATE CONSTRAINT once the old rows are fixed by hand
+ALTER TABLE "transaction" ALTER COLUMN transaction_type DROP DEFAULT;
+ALTER TABLE "transaction" ADD CONSTRAINT transaction_transaction_type_check
+  CHECK (transaction_type IN ('income', 'expense')) NOT VALID;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "transaction" DROP CONSTRAINT IF EXISTS transaction_transaction_type_check;
+ALTER TABLE "transaction" ALTER COLUMN transaction_type SET DEFAULT '';
+ALTER TABLE "transaction" ALTER COLUMN amount DROP NOT NULL;
+ALTER TABLE "transaction" DROP CONSTRAINT IF EXISTS transaction_date_check;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every listing and summary reads the transactions of one spender, by
+-- period or by type
+CREATE INDEX IF NOT EXISTS transaction_spender_id_date_idx ON "transaction" (spender_id, date);
+CREATE INDEX IF NOT EXISTS transaction_spender_id_transaction_type_idx ON "transaction" (spender_id, transaction_type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transaction_spender_id_transaction_type_idx;
+DROP INDEX IF EXISTS transaction_spender_id_date_idx;
+-- +goose StatementEnd
@@ -0,0 +1,197 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/pressly/goose/v3"
+)
+
+// Usage lists the commands understood by Run.
+const Usage = `usage: migrate COMMAND
+
+commands:
+  status         list every migration and when it was applied
+  version        print the version of the database
+  up             apply all pending migrations
+  up-to VERSION  apply the pending migrations up to VERSION
+  down           roll back the last migration
+  down-to VERSION
+                 roll back the migrations after VERSION
+  redo           roll back the last migration and apply it again
+  validate       check the migration files without a database
+  create NAME    write an empty migration into the migration directory`
+
+const template = `-- +goose Up
+-- +goose StatementBegin
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- +goose StatementEnd
+`
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// NeedsDB tells whether the migrate command in args works on the database,
+// so the caller only connects when it has to.
+func NeedsDB(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "status", "version", "up", "up-to", "down", "down-to", "redo":
+		return true
+	}
+	return false
+}
+
+// Run carries out a migrate command on db and reports to out. validate and
+// create do not touch the database, db may be nil for them.
+func Run(ctx context.Context, db *sql.DB, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(Usage)
+	}
+
+	command, args := args[0], args[1:]
+	var version int64
+	switch command {
+	case "status", "version", "up", "down", "redo", "validate":
+		if len(args) != 0 {
+			return fmt.Errorf("%s takes no arguments", command)
+		}
+	case "up-to", "down-to":
+		if len(args) != 1 {
+			return fmt.Errorf("%s needs a VERSION", command)
+		}
+		var err error
+		if version, err = strconv.ParseInt(args[0], 10, 64); err != nil || version < 0 {
+			return fmt.Errorf("invalid version: %s", args[0])
+		}
+	case "create":
+		if len(args) != 1 || !migrationName.MatchString(args[0]) {
+			return errors.New("create needs a NAME of lower case letters, digits and underscores")
+		}
+		return create(out, "migration", args[0])
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, Usage)
+	}
+
+	if command == "validate" {
+		if err := Validate(); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "migrations are valid")
+		return nil
+	}
+
+	p, err := NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	var results []*goose.MigrationResult
+	switch command {
+	case "status":
+		return status(ctx, p, out)
+	case "version":
+		current, err := p.GetDBVersion(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, current)
+		return nil
+	case "up":
+		results, err = p.Up(ctx)
+	case "up-to":
+		results, err = p.UpTo(ctx, version)
+	case "down":
+		var r *goose.MigrationResult
+		if r, err = p.Down(ctx); r != nil {
+			results = append(results, r)
+		}
+	case "down-to":
+		results, err = p.DownTo(ctx, version)
+	case "redo":
+		results, err = redo(ctx, p)
+	}
+	for _, r := range results {
+		fmt.Fprintln(out, r)
+	}
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(out, "nothing to do")
+	}
+	return nil
+}
+
+func status(ctx context.Context, p *goose.Provider, out io.Writer) error {
+	statuses, err := p.Status(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%-24s   %s\n", "Applied At", "Migration")
+	for _, s := range statuses {
+		appliedAt := "Pending"
+		if s.State == goose.StateApplied {
+			appliedAt = s.AppliedAt.Format("Mon Jan _2 15:04:05 2006")
+		}
+		fmt.Fprintf(out, "%-24s -- %s\n", appliedAt, filepath.Base(s.Source.Path))
+	}
+	return nil
+}
+
+func redo(ctx context.Context, p *goose.Provider) ([]*goose.MigrationResult, error) {
+	down, err := p.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := p.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// create writes the next numbered migration into dir, which has to be the
+// migration directory of the source tree for it to be built in.
+func create(out io.Writer, dir, name string) error {
+	names, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no migrations found in %s, run create from the root of the source tree", dir)
+	}
+	var last int64
+	for _, n := range names {
+		if m := fileName.FindStringSubmatch(filepath.Base(n)); m != nil {
+			v, _ := strconv.ParseInt(m[1], 10, 64)
+			last = max(last, v)
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%02d_%s.sql", last+1, name))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(template); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "created", path)
+	return nil
+}
@@ -1,21 +1,39 @@
 package migration
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 
 	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
 )
 
 //go:embed *.sql
 var embedMigrations embed.FS
 
+// ErrPending is returned by EnsureCurrent when the database is behind the
+// migrations built into the binary.
+var ErrPending = errors.New("database has pending migrations, run the migrate up command first")
+
+// NewProvider returns a goose provider over the embedded migrations. It
+// holds a Postgres advisory lock while migrating, so servers starting
+// together apply every migration exactly once.
+func NewProvider(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, db, embedMigrations, goose.WithSessionLocker(locker))
+}
+
 func ApplyMigrations(db *sql.DB) error {
-	goose.SetBaseFS(embedMigrations)
-	if err := goose.SetDialect("postgres"); err != nil {
+	p, err := NewProvider(db)
+	if err != nil {
 		return err
 	}
-	if err := goose.Up(db, "."); err != nil {
+	if _, err := p.Up(context.Background()); err != nil {
 		return err
 	}
 	return nil
@@ -31,3 +49,20 @@ func RollbackMigrations(db *sql.DB) error {
 	}
 	return nil
 }
+
+// EnsureCurrent fails with ErrPending unless every embedded migration has
+// been applied, for servers that must not migrate on their own.
+func EnsureCurrent(ctx context.Context, db *sql.DB) error {
+	p, err := NewProvider(db)
+	if err != nil {
+		return err
+	}
+	pending, err := p.HasPending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return ErrPending
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package migration
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_[a-z0-9_]+\.sql$`)
+
+// Validate checks the embedded migrations without a database.
+func Validate() error {
+	return validate(embedMigrations)
+}
+
+// validate checks every migration in fsys is named NN_name.sql with a
+// version of its own, and has an Up and a Down section that each run a
+// statement between balanced StatementBegin and StatementEnd annotations.
+func validate(fsys fs.FS) error {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.New("no migrations found")
+	}
+
+	var errs []error
+	versions := map[int64]string{}
+	for _, name := range names {
+		m := fileName.FindStringSubmatch(name)
+		if m == nil {
+			errs = append(errs, fmt.Errorf("%s: name must look like 01_create_table.sql", name))
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		if other, ok := versions[version]; ok {
+			errs = append(errs, fmt.Errorf("%s: version %d is taken by %s", name, version, other))
+		}
+		versions[version] = name
+
+		if err := validateFile(fsys, name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateFile(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var section string
+	var inStatement bool
+	statements := map[string]int{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "-- +goose "):
+			switch annotation := strings.TrimPrefix(line, "-- +goose "); annotation {
+			case "Up", "Down":
+				if inStatement {
+					return fmt.Errorf("%s starts before StatementEnd", annotation)
+				}
+				if _, ok := statements[annotation]; ok || (annotation == "Up" && section != "") {
+					return fmt.Errorf("unexpected %s section", annotation)
+				}
+				section = annotation
+				statements[section] = 0
+			case "StatementBegin":
+				if section == "" || inStatement {
+					return errors.New("unexpected StatementBegin")
+				}
+				inStatement = true
+			case "StatementEnd":
+				if !inStatement {
+					return errors.New("StatementEnd without StatementBegin")
+				}
+				inStatement = false
+			case "NO TRANSACTION", "ENVSUB ON", "ENVSUB OFF":
+			default:
+				return fmt.Errorf("unknown annotation %q", annotation)
+			}
+		case line == "" || strings.HasPrefix(line, "--"):
+		case section == "":
+			return errors.New("statement before the Up section")
+		default:
+			statements[section]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if inStatement {
+		return errors.New("StatementBegin without StatementEnd")
+	}
+	for _, section := range []string{"Up", "Down"} {
+		if n, ok := statements[section]; !ok {
+			return fmt.Errorf("missing %s section", section)
+		} else if n == 0 {
+			return fmt.Errorf("empty %s section", section)
+		}
+	}
+	return nil
+}
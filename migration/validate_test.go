@@ -0,0 +1,76 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	up   = "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE t (id INT);\n-- +goose StatementEnd\n"
+	down = "\n-- +goose Down\n-- +goose StatementBegin\nDROP TABLE t;\n-- +goose StatementEnd\n"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("embedded migrations are valid", func(t *testing.T) {
+		assert.NoError(t, Validate())
+	})
+
+	for name, tc := range map[string]struct {
+		files fstest.MapFS
+		err   string
+	}{
+		"empty down section": {
+			files: fstest.MapFS{"01_t.sql": {Data: []byte(up + "\n-- +goose Down\n-- +goose StatementBegin\n-- +goose StatementEnd\n")}},
+			err:   "01_t.sql: empty Down section",
+		},
+		"missing down section": {
+			files: fstest.MapFS{"01_t.sql": {Data: []byte(up)}},
+			err:   "01_t.sql: missing Down section",
+		},
+		"unbalanced statement": {
+			files: fstest.MapFS{"01_t.sql": {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE t (id INT);\n" + down)}},
+			err:   "01_t.sql: Down starts before StatementEnd",
+		},
+		"duplicate version": {
+			files: fstest.MapFS{"01_t.sql": {Data: []byte(up + down)}, "1_u.sql": {Data: []byte(up + down)}},
+			err:   "1_u.sql: version 1 is taken by 01_t.sql",
+		},
+		"bad name": {
+			files: fstest.MapFS{"01-t.sql": {Data: []byte(up + down)}},
+			err:   "01-t.sql: name must look like 01_create_table.sql",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualError(t, validate(tc.files), tc.err)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	for _, args := range [][]string{nil, {"sideways"}, {"up-to"}, {"down-to", "x"}, {"status", "now"}, {"create", "Bad Name"}} {
+		t.Run(fmt.Sprint("fail on ", args), func(t *testing.T) {
+			assert.Error(t, Run(context.Background(), nil, &bytes.Buffer{}, args))
+		})
+	}
+
+	t.Run("only database commands need a database", func(t *testing.T) {
+		assert.True(t, NeedsDB([]string{"up"}))
+		assert.True(t, NeedsDB([]string{"down-to", "3"}))
+		assert.False(t, NeedsDB([]string{"validate"}))
+		assert.False(t, NeedsDB([]string{"create", "add_spender_phone"}))
+		assert.False(t, NeedsDB(nil))
+	})
+
+	t.Run("validate without a database", func(t *testing.T) {
+		var out bytes.Buffer
+		err := Run(context.Background(), nil, &out, []string{"validate"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "migrations are valid\n", out.String())
+	})
+}
@@ -0,0 +1,164 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/spender"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/validate"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures are the spenders, each with its transactions, written by Apply.
+type Fixtures struct {
+	Spenders []Spender `json:"spenders"`
+}
+
+type Spender struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"`
+
+	Transactions []Transaction `json:"transactions"`
+}
+
+type Transaction struct {
+	Date            time.Time    `json:"date" validate:"required"`
+	Amount          money.Amount `json:"amount" validate:"gt=0,money"`
+	Category        string       `json:"category" validate:"required,max=50,nocontrol"`
+	TransactionType string       `json:"transaction_type" validate:"oneof=income expense"`
+	Note            string       `json:"note" validate:"max=255"`
+	// Currency defaults to the home currency of the spender when left empty.
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+}
+
+// Result counts what Apply wrote. A spender whose email is already taken
+// is skipped along with its transactions, so seeding twice adds nothing.
+type Result struct {
+	Spenders     int
+	Skipped      int
+	Transactions int
+}
+
+const (
+	spenderStmt = `INSERT INTO spender (name, email, currency, timezone) VALUES ($1, $2, $3, $4) ON CONFLICT ((lower(email))) DO NOTHING RETURNING id;`
+	transStmt   = `INSERT INTO transaction (date, amount, category, transaction_type, note, spender_id, currency) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+)
+
+// Load reads fixtures from a .yaml, .yml or .json file and checks them
+// against the same rules as the API.
+func Load(path string) (Fixtures, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		if b, err = yamlToJSON(b); err != nil {
+			return Fixtures{}, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return Fixtures{}, fmt.Errorf("%s: fixtures must be a .yaml, .yml or .json file", path)
+	}
+
+	var f Fixtures
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return Fixtures{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := f.normalize(); err != nil {
+		return Fixtures{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// yamlToJSON converts a YAML document to JSON, so both formats decode with
+// the JSON tags and types of the API.
+func yamlToJSON(b []byte) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// normalize fills in the defaults the API would and validates every
+// spender and transaction, naming the first one that is wrong.
+func (f *Fixtures) normalize() error {
+	v := validate.New(nil)
+	for i := range f.Spenders {
+		sp := &f.Spenders[i]
+		sp.Email = strings.ToLower(strings.TrimSpace(sp.Email))
+		sp.Currency = strings.ToUpper(sp.Currency)
+		if sp.Currency == "" {
+			sp.Currency = money.DefaultCurrency
+		}
+		if sp.Timezone == "" {
+			sp.Timezone = spender.DefaultTimezone
+		}
+		if err := v.Validate(sp); err != nil {
+			return fmt.Errorf("spenders[%d]: %w", i, err)
+		}
+
+		for j := range sp.Transactions {
+			t := &sp.Transactions[j]
+			t.Currency = strings.ToUpper(t.Currency)
+			if t.Currency == "" {
+				t.Currency = sp.Currency
+			}
+			if err := v.Validate(t); err != nil {
+				return fmt.Errorf("spenders[%d].transactions[%d]: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply writes the fixtures in one database transaction, nothing is kept
+// when any of them fails.
+func Apply(ctx context.Context, db *sql.DB, f Fixtures) (Result, error) {
+	var r Result
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return r, err
+	}
+	defer tx.Rollback()
+
+	for _, sp := range f.Spenders {
+		var id int64
+		err := tx.QueryRowContext(ctx, spenderStmt, sp.Name, sp.Email, sp.Currency, sp.Timezone).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			r.Skipped++
+			continue
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("spender %s: %w", sp.Email, err)
+		}
+		r.Spenders++
+
+		for _, t := range sp.Transactions {
+			if _, err := tx.ExecContext(ctx, transStmt, t.Date, t.Amount, t.Category, t.TransactionType, t.Note, id, t.Currency); err != nil {
+				return Result{}, fmt.Errorf("transaction of %s: %w", sp.Email, err)
+			}
+			r.Transactions++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Result{}, err
+	}
+	return r, nil
+}
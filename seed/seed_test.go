@@ -0,0 +1,118 @@
+package seed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/KKGo-Software-engineering/workshop-summer/api/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("load yaml fixtures with defaults filled in", func(t *testing.T) {
+		f, err := Load("testdata/fixtures.yaml")
+
+		assert.NoError(t, err)
+		assert.Len(t, f.Spenders, 2)
+		hong := f.Spenders[0]
+		assert.Equal(t, "hong@jot.ok", hong.Email)
+		assert.Equal(t, "THB", hong.Currency)
+		assert.Equal(t, "Asia/Bangkok", hong.Timezone)
+		assert.Equal(t, Transaction{
+			Date:            time.Date(2024, 4, 25, 0, 0, 0, 0, time.UTC),
+			Amount:          money.MustParse("50000.50"),
+			Category:        "Salary",
+			TransactionType: "income",
+			Currency:        "THB",
+		}, hong.Transactions[1])
+		assert.Equal(t, "JPY", f.Spenders[1].Currency)
+	})
+
+	t.Run("load json fixtures", func(t *testing.T) {
+		f, err := Load("testdata/fixtures.json")
+
+		assert.NoError(t, err)
+		assert.Len(t, f.Spenders, 1)
+		assert.Equal(t, money.MustParse("1200"), f.Spenders[0].Transactions[0].Amount)
+	})
+
+	for name, tc := range map[string]struct {
+		file, body, err string
+	}{
+		"unknown field": {
+			file: "f.json",
+			body: `{"spenders": [{"name": "HongJot", "email": "hong@jot.ok", "id": 1}]}`,
+			err:  `json: unknown field "id"`,
+		},
+		"invalid transaction": {
+			file: "f.yml",
+			body: "spenders:\n  - name: HongJot\n    email: hong@jot.ok\n    transactions:\n      - date: 2024-04-30\n        amount: 0\n        category: Food\n        transaction_type: expense\n",
+			err:  "spenders[0].transactions[0]: amount must be greater than 0",
+		},
+		"unsupported format": {
+			file: "f.csv",
+			body: "name,email",
+			err:  "fixtures must be a .yaml, .yml or .json file",
+		},
+	} {
+		t.Run("load fail on "+name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			assert.NoError(t, os.WriteFile(path, []byte(tc.body), 0o600))
+
+			_, err := Load(path)
+
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	date := time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC)
+	f := Fixtures{Spenders: []Spender{
+		{Name: "HongJot", Email: "hong@jot.ok", Currency: "THB", Timezone: "Asia/Bangkok", Transactions: []Transaction{
+			{Date: date, Amount: money.MustParse("1200"), Category: "Food", TransactionType: "expense", Currency: "THB"},
+		}},
+		{Name: "Tanaka", Email: "tanaka@jot.ok", Currency: "JPY", Timezone: "Asia/Tokyo", Transactions: []Transaction{
+			{Date: date, Amount: money.MustParse("500"), Category: "Food", TransactionType: "expense", Currency: "JPY"},
+		}},
+	}}
+
+	t.Run("apply skips spenders that already exist", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(spenderStmt).WithArgs("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(transStmt).WithArgs(date, money.MustParse("1200"), "Food", "expense", "", int64(1), "THB").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(spenderStmt).WithArgs("Tanaka", "tanaka@jot.ok", "JPY", "Asia/Tokyo").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		r, err := Apply(context.Background(), db, f)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Result{Spenders: 1, Skipped: 1, Transactions: 1}, r)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("apply keeps nothing when a write fails", func(t *testing.T) {
+		db, mock, _ := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		defer db.Close()
+		mock.ExpectBegin()
+		mock.ExpectQuery(spenderStmt).WithArgs("HongJot", "hong@jot.ok", "THB", "Asia/Bangkok").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(transStmt).WithArgs(date, money.MustParse("1200"), "Food", "expense", "", int64(1), "THB").
+			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+
+		_, err := Apply(context.Background(), db, f)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}